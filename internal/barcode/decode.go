@@ -0,0 +1,131 @@
+package barcode
+
+import (
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/makiuchi-d/gozxing"
+	"github.com/makiuchi-d/gozxing/datamatrix"
+	"github.com/makiuchi-d/gozxing/oned"
+	"github.com/makiuchi-d/gozxing/qrcode"
+)
+
+// gs1GTIN - GS1 QR / DataMatrix ("Asl belgisi" markirovkasi): (01) + 14 raqamli GTIN
+var gs1GTIN = regexp.MustCompile(`^(?:\]d2|\]Q3)?\x1d?01(\d{14})`)
+
+// Decode - rasmdagi shtrix-kodlarni o'qish (EAN-13/8, UPC-A/E, QR, DataMatrix).
+// Tashqi servis ishlatilmaydi. GS1 kodlardan GTIN ajratib olinadi;
+// topilmasa bo'sh ro'yxat qaytadi.
+func Decode(r io.Reader) ([]string, error) {
+	img, _, err := image.Decode(r)
+	if err != nil {
+		return nil, fmt.Errorf("rasm o'qilmadi: %v", err)
+	}
+
+	source := gozxing.NewLuminanceSourceFromImage(img)
+	binarizers := []gozxing.Binarizer{
+		gozxing.NewHybridBinarizer(source),
+		gozxing.NewGlobalHistgramBinarizer(source), // 1D kodlar uchun ko'pincha yaxshiroq
+	}
+
+	hints := map[gozxing.DecodeHintType]interface{}{
+		gozxing.DecodeHintType_TRY_HARDER: true,
+	}
+	readers := []gozxing.Reader{
+		oned.NewMultiFormatUPCEANReader(hints),
+		qrcode.NewQRCodeReader(),
+		datamatrix.NewDataMatrixReader(),
+	}
+
+	var codes []string
+	seen := make(map[string]bool)
+	for _, binarizer := range binarizers {
+		bmp, err := gozxing.NewBinaryBitmap(binarizer)
+		if err != nil {
+			continue
+		}
+		for _, reader := range readers {
+			result, err := reader.Decode(bmp, hints)
+			if err != nil {
+				continue
+			}
+			text := result.GetText()
+			if result.GetBarcodeFormat() == gozxing.BarcodeFormat_UPC_E {
+				text = expandUPCE(text)
+			}
+			code := gtin(text)
+			if code != "" && !seen[code] {
+				seen[code] = true
+				codes = append(codes, code)
+			}
+		}
+	}
+	return codes, nil
+}
+
+// gtin - GS1 matnidan GTIN, aks holda matnning o'zi. GTIN-14 boshidagi nollar EAN-13 yoki
+// EAN-8 uzunligigacha tashlanadi (000000xxxxxxxx - EAN-8). Nazorat raqami noto'g'ri bo'lsa
+// GTIN-14 o'zgarishsiz qaytadi.
+func gtin(text string) string {
+	text = strings.TrimSpace(text)
+	m := gs1GTIN.FindStringSubmatch(text)
+	if m == nil {
+		return text
+	}
+	code := m[1]
+	if !ValidCheckDigit(code) {
+		return code
+	}
+	if strings.HasPrefix(code, "000000") {
+		return code[6:]
+	}
+	if strings.HasPrefix(code, "0") {
+		return code[1:]
+	}
+	return code
+}
+
+// expandUPCE - UPC-E (8 raqam: tizim raqami, 6 raqam, nazorat) -> UPC-A (12 raqam).
+// Nazorat raqami ikkalasida bir xil; boshqa uzunlik o'zgarishsiz qaytadi.
+func expandUPCE(code string) string {
+	if len(code) != 8 {
+		return code
+	}
+	d := code[1:7]
+	var body string
+	switch d[5] {
+	case '0', '1', '2':
+		body = d[0:2] + string(d[5]) + "0000" + d[2:5]
+	case '3':
+		body = d[0:3] + "00000" + d[3:5]
+	case '4':
+		body = d[0:4] + "00000" + d[4:5]
+	default:
+		body = d[0:5] + "0000" + d[5:6]
+	}
+	return code[0:1] + body + code[7:8]
+}
+
+// ValidCheckDigit - GS1 nazorat raqami (EAN-8, EAN-13, UPC-A, GTIN-14):
+// o'ngdan chapga 3 va 1 og'irliklar
+func ValidCheckDigit(code string) bool {
+	if len(code) < 2 {
+		return false
+	}
+	sum := 0
+	weight := 3
+	for i := len(code) - 2; i >= 0; i-- {
+		if code[i] < '0' || code[i] > '9' {
+			return false
+		}
+		sum += int(code[i]-'0') * weight
+		weight = 4 - weight
+	}
+	check := (10 - sum%10) % 10
+	return check == int(code[len(code)-1]-'0')
+}
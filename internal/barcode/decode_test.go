@@ -0,0 +1,65 @@
+package barcode
+
+import "testing"
+
+func TestGTIN(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"0104601234567893", "4601234567893"},          // GTIN-14 -> EAN-13
+		{"0100000096385074", "96385074"},               // GTIN-14 -> EAN-8
+		{"0100012345678905", "0012345678905"},          // UPC-A -> 13 raqam (bazadagi ko'rinish)
+		{"0112345678901231", "12345678901231"},         // haqiqiy GTIN-14 (qadoq darajasi)
+		{"0104601234567890", "04601234567890"},         // nazorat raqami xato - o'zgarishsiz
+		{"]d20104601234567893215abc", "4601234567893"}, // DataMatrix prefiksi va qo'shimcha maydonlar
+		{"\x1d0104601234567893", "4601234567893"},      // GS ajratuvchi
+		{" 4601234567893 ", "4601234567893"},           // oddiy EAN - matnning o'zi
+		{"https://example.com", "https://example.com"}, // GS1 emas
+	}
+	for _, tt := range tests {
+		if got := gtin(tt.in); got != tt.want {
+			t.Errorf("gtin(%q) = %q, kutilgan %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestExpandUPCE(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"01234565", "012345000065"}, // oxirgi raqam 5-9
+		{"01234505", "012000003455"}, // 0-2
+		{"01234531", "012300000451"}, // 3
+		{"01234542", "012340000052"}, // 4
+		{"4601234567893", "4601234567893"},
+	}
+	for _, tt := range tests {
+		if got := expandUPCE(tt.in); got != tt.want {
+			t.Errorf("expandUPCE(%q) = %q, kutilgan %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestValidCheckDigit(t *testing.T) {
+	tests := []struct {
+		in   string
+		want bool
+	}{
+		{"4601234567893", true},
+		{"4601234567890", false},
+		{"96385074", true},
+		{"96385075", false},
+		{"012345000065", true},
+		{"04601234567893", true},
+		{"46012a4567893", false},
+		{"", false},
+		{"7", false},
+	}
+	for _, tt := range tests {
+		if got := ValidCheckDigit(tt.in); got != tt.want {
+			t.Errorf("ValidCheckDigit(%q) = %v, kutilgan %v", tt.in, got, tt.want)
+		}
+	}
+}
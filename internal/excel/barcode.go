@@ -0,0 +1,45 @@
+package excel
+
+import (
+	"strconv"
+	"strings"
+
+	"testuchun/internal/barcode"
+)
+
+// NormalizeBarcode - EAN-8 / EAN-13 / UPC-A kodini tekshirish.
+// UPC-A (12 raqam) EAN-13 ga keltiriladi; nazorat raqami noto'g'ri bo'lsa false.
+func NormalizeBarcode(s string) (string, bool) {
+	s = strings.Map(func(r rune) rune {
+		if r == ' ' || r == '-' {
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(s))
+
+	// Excel katta sonlarni "4.60123456789E+12" ko'rinishida berishi mumkin
+	if strings.ContainsAny(s, "eE") {
+		if f, err := strconv.ParseFloat(s, 64); err == nil && f > 0 {
+			s = strconv.FormatFloat(f, 'f', 0, 64)
+		}
+	}
+
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return "", false
+		}
+	}
+
+	switch len(s) {
+	case 12:
+		s = "0" + s
+	case 8, 13:
+	default:
+		return "", false
+	}
+
+	if !barcode.ValidCheckDigit(s) {
+		return "", false
+	}
+	return s, true
+}
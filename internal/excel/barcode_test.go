@@ -0,0 +1,32 @@
+package excel
+
+import "testing"
+
+func TestNormalizeBarcode(t *testing.T) {
+	tests := []struct {
+		in     string
+		want   string
+		wantOK bool
+	}{
+		{"4601234567893", "4601234567893", true},      // EAN-13
+		{"96385074", "96385074", true},                // EAN-8
+		{"012345678905", "0012345678905", true},       // UPC-A -> EAN-13
+		{" 460 1234-567893 ", "4601234567893", true},  // bo'shliq va chiziqcha
+		{"4.601234567893E+12", "4601234567893", true}, // Excel ilmiy ko'rinishi
+		{"4,601234567893E+12", "", false},
+		{"4601234567890", "", false}, // nazorat raqami xato
+		{"96385075", "", false},
+		{"012345678900", "", false},
+		{"460123456789", "", false},   // 12 raqam, UPC-A sifatida nazorat xato
+		{"1234567", "", false},        // uzunlik xato
+		{"46012345678931", "", false}, // 14 raqam
+		{"", "", false},
+		{"ABC1234567893", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := NormalizeBarcode(tt.in)
+		if ok != tt.wantOK || got != tt.want {
+			t.Errorf("NormalizeBarcode(%q) = %q, %v; kutilgan %q, %v", tt.in, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package excel
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Batch - dorining bitta partiyasi (seriya va yaroqlilik muddati)
+type Batch struct {
+	Series string
+	Expiry time.Time // nol qiymat - muddat ko'rsatilmagan
+	Count  int
+}
+
+// Expired - partiya muddati o'tganmi (muddat kuni hali yaroqli hisoblanadi)
+func (b Batch) Expired(now time.Time) bool {
+	if b.Expiry.IsZero() {
+		return false
+	}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	return b.Expiry.Before(today)
+}
+
+// key - fayl ichida bir xil partiyani aniqlash uchun
+func (b Batch) key() string {
+	return b.Series + "|" + b.Expiry.Format("2006-01-02")
+}
+
+// mergeBatch - dorining yangi partiyasini qo'shish (bir xil partiya bo'lsa almashtiriladi).
+// Narx va boshqa maydonlar oxirgi qatordan olinadi, miqdor partiyalardan qayta hisoblanadi.
+func mergeBatch(dst *Medicine, src Medicine) {
+	batches := dst.Batches
+	added := src.Batches[0]
+
+	replaced := false
+	for i := range batches {
+		if batches[i].key() == added.key() {
+			batches[i] = added
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		batches = append(batches, added)
+	}
+
+	*dst = src
+	dst.Batches = batches
+	dst.Count = 0
+	for _, b := range batches {
+		dst.Count += b.Count
+	}
+}
+
+// AvailableCountSQL - muddati o'tmagan partiyalar yig'indisi; partiyasiz dorida medicines.count.
+// "m" - medicines jadvali aliasi.
+const AvailableCountSQL = `CASE WHEN EXISTS (
+		SELECT 1 FROM medicine_batches b WHERE b.pharmacy_id = m.pharmacy_id AND b.name = m.name
+	) THEN (
+		SELECT COALESCE(SUM(b.count), 0)::INT FROM medicine_batches b
+		WHERE b.pharmacy_id = m.pharmacy_id AND b.name = m.name
+		  AND (b.expiry_date IS NULL OR b.expiry_date >= CURRENT_DATE)
+	) ELSE m.count END`
+
+// NearestExpirySQL - eng yaqin (muddati o'tmagan) partiya muddati, bo'lmasa NULL
+const NearestExpirySQL = `(
+		SELECT MIN(b.expiry_date) FROM medicine_batches b
+		WHERE b.pharmacy_id = m.pharmacy_id AND b.name = m.name AND b.count > 0
+		  AND b.expiry_date >= CURRENT_DATE
+	)`
+
+var (
+	dateDMY      = regexp.MustCompile(`^(\d{1,2})[./-](\d{1,2})[./-](\d{4})$`) // 31.12.2026
+	dateDMYShort = regexp.MustCompile(`^(\d{1,2})\.(\d{1,2})\.(\d{2})$`)       // 31.12.26
+	dateMDYShort = regexp.MustCompile(`^(\d{1,2})[-/](\d{1,2})[-/](\d{2})$`)   // 12-31-26 (excelize standart formati)
+	dateYMD      = regexp.MustCompile(`^(\d{4})[./-](\d{1,2})[./-](\d{1,2})$`) // 2026-12-31
+	dateMY       = regexp.MustCompile(`^(\d{1,2})[./-](\d{4})$`)               // 12.2026 - oy oxiri
+)
+
+// parseExpiry - yaroqlilik muddatini o'qish. Qisqa yilli noaniq sana ("03-04-26") "-" yoki "/"
+// bilan MDY deb o'qiladi (excelize sanani shunday beradi), "." bilan esa DMY ("03.04.26").
+func parseExpiry(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	// "31.12.2026 0:00:00" kabi vaqt qismi tashlanadi
+	if i := strings.IndexByte(s, ' '); i > 0 {
+		s = s[:i]
+	}
+
+	atoi := func(v string) int {
+		n, _ := strconv.Atoi(v)
+		return n
+	}
+
+	var y, m, d int
+	switch {
+	case dateDMY.MatchString(s):
+		p := dateDMY.FindStringSubmatch(s)
+		d, m, y = atoi(p[1]), atoi(p[2]), atoi(p[3])
+	case dateDMYShort.MatchString(s):
+		p := dateDMYShort.FindStringSubmatch(s)
+		d, m, y = atoi(p[1]), atoi(p[2]), 2000+atoi(p[3])
+	case dateMDYShort.MatchString(s):
+		p := dateMDYShort.FindStringSubmatch(s)
+		m, d, y = atoi(p[1]), atoi(p[2]), 2000+atoi(p[3])
+	case dateYMD.MatchString(s):
+		p := dateYMD.FindStringSubmatch(s)
+		y, m, d = atoi(p[1]), atoi(p[2]), atoi(p[3])
+	case dateMY.MatchString(s):
+		p := dateMY.FindStringSubmatch(s)
+		m, y = atoi(p[1]), atoi(p[2])
+		if m < 1 || m > 12 {
+			return time.Time{}, fmt.Errorf("noto'g'ri sana: %s", s)
+		}
+		// Keyingi oyning 0-kuni - shu oyning oxirgi kuni
+		return time.Date(y, time.Month(m)+1, 0, 0, 0, 0, 0, time.UTC), nil
+	default:
+		// Excel sana seriya raqami (1900 tizimi)
+		if serial, err := strconv.Atoi(s); err == nil && serial > 30000 && serial < 80000 {
+			return time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC).AddDate(0, 0, serial), nil
+		}
+		return time.Time{}, fmt.Errorf("noto'g'ri sana: %s", s)
+	}
+
+	t := time.Date(y, time.Month(m), d, 0, 0, 0, 0, time.UTC)
+	if t.Day() != d || int(t.Month()) != m {
+		return time.Time{}, fmt.Errorf("noto'g'ri sana: %s", s)
+	}
+	return t, nil
+}
+
+// nullDate - nol sana bazaga NULL bo'lib yoziladi
+func nullDate(t time.Time) interface{} {
+	if t.IsZero() {
+		return nil
+	}
+	return t.Format("2006-01-02")
+}
+
+// replaceBatches - fayldagi dorilarning partiyalarini yangilash.
+// Fayldagi har bir dori uchun eski partiyalar o'chiriladi (fayl to'liq qoldiqni beradi).
+func replaceBatches(tx *sql.Tx, pharmacyID int, names []string, medicines []Medicine) error {
+	_, err := tx.Exec(
+		"DELETE FROM medicine_batches WHERE pharmacy_id = $1 AND name = ANY($2)",
+		pharmacyID, pq.Array(names),
+	)
+	if err != nil {
+		return err
+	}
+
+	const batchSize = 200
+	var valueStrings []string
+	var valueArgs []interface{}
+
+	flush := func() error {
+		if len(valueStrings) == 0 {
+			return nil
+		}
+		_, err := tx.Exec(`
+			INSERT INTO medicine_batches (pharmacy_id, name, series, expiry_date, count)
+			VALUES `+strings.Join(valueStrings, ", "), valueArgs...)
+		valueStrings, valueArgs = valueStrings[:0], valueArgs[:0]
+		return err
+	}
+
+	for _, med := range medicines {
+		for _, b := range med.Batches {
+			argPos := len(valueArgs) + 1
+			valueStrings = append(valueStrings, fmt.Sprintf(
+				"($%d, $%d, $%d, $%d::DATE, $%d)", argPos, argPos+1, argPos+2, argPos+3, argPos+4,
+			))
+			valueArgs = append(valueArgs, pharmacyID, med.Name, b.Series, nullDate(b.Expiry), b.Count)
+			if len(valueStrings) == batchSize {
+				if err := flush(); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return flush()
+}
@@ -0,0 +1,61 @@
+package excel
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseExpiry(t *testing.T) {
+	date := func(y, m, d int) time.Time { return time.Date(y, time.Month(m), d, 0, 0, 0, 0, time.UTC) }
+
+	tests := []struct {
+		in      string
+		want    time.Time
+		wantErr bool
+	}{
+		// DMY, 4 xonali yil - har qanday ajratuvchi
+		{"31.12.2026", date(2026, 12, 31), false},
+		{"1/2/2027", date(2027, 2, 1), false},
+		{"03-04-2026", date(2026, 4, 3), false},
+		{"31.12.2026 0:00:00", date(2026, 12, 31), false},
+		// Qisqa yil, nuqta - DMY
+		{"31.12.26", date(2026, 12, 31), false},
+		{"03.04.26", date(2026, 4, 3), false},
+		// Qisqa yil, "-" yoki "/" - excelize standart formati MDY (noaniq sanada MDY yutadi)
+		{"12-31-26", date(2026, 12, 31), false},
+		{"03-04-26", date(2026, 3, 4), false},
+		{"03/04/26", date(2026, 3, 4), false},
+		{"31-12-26", time.Time{}, true}, // MDY bo'yicha 31-oy yo'q
+		// YMD
+		{"2026-12-31", date(2026, 12, 31), false},
+		{"2026.02.28", date(2026, 2, 28), false},
+		// MY - oy oxiri
+		{"12.2026", date(2026, 12, 31), false},
+		{"02/2028", date(2028, 2, 29), false},
+		{"13.2026", time.Time{}, true},
+		// Excel seriya raqami
+		{"46387", date(2026, 12, 31), false},
+		{"100", time.Time{}, true},
+		// Noto'g'ri sanalar
+		{"31.02.2026", time.Time{}, true},
+		{"", time.Time{}, true},
+		{"yaroqli", time.Time{}, true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseExpiry(tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseExpiry(%q) = %s, xato kutilgan edi", tt.in, got.Format("2006-01-02"))
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseExpiry(%q) xato: %v", tt.in, err)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("parseExpiry(%q) = %s, kutilgan %s", tt.in, got.Format("2006-01-02"), tt.want.Format("2006-01-02"))
+		}
+	}
+}
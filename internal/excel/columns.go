@@ -0,0 +1,163 @@
+package excel
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Ustun maydonlari
+const (
+	fieldNum          = "num"
+	fieldName         = "name"
+	fieldCount        = "count"
+	fieldPrice        = "price"
+	fieldManufacturer = "manufacturer"
+	fieldBarcode      = "barcode"
+	fieldSeries       = "series"
+	fieldExpiry       = "expiry"
+	fieldCategory     = "category"
+)
+
+// headerScanRows - sarlavha qatori shu qatorlar ichidan qidiriladi
+const headerScanRows = 20
+
+// headerAliases - sarlavha nomlari (rus, o'zbek lotin va kirill variantlari), kichik harflarda
+var headerAliases = map[string][]string{
+	fieldNum:          {"№", "n", "#", "no", "п/п", "№ п/п", "t/r", "т/р"},
+	fieldName:         {"наименование", "название", "товар", "препарат", "nomi", "nomlanishi", "номи", "номланиши", "mahsulot", "маҳсулот", "dori nomi"},
+	fieldCount:        {"кол-во", "кол.", "количество", "остаток", "soni", "сони", "miqdori", "miqdor", "миқдори", "qoldiq", "қолдиқ"},
+	fieldPrice:        {"цена", "narxi", "narx", "нархи", "нарх"},
+	fieldManufacturer: {"производитель", "изготовитель", "завод", "фирма", "ishlab chiqaruvchi", "ишлаб чиқарувчи"},
+	fieldBarcode:      {"штрих-код", "штрихкод", "штрих код", "шк", "ean", "barcode", "shtrix-kod", "shtrix kod", "штрих-коди"},
+	fieldSeries:       {"серия", "партия", "seriya", "серия/партия", "partiya", "lot"},
+	fieldExpiry:       {"срок годности", "годен до", "срок", "yaroqlilik muddati", "yaroqlilik", "яроқлилик муддати", "muddati", "exp"},
+	fieldCategory:     {"категория", "группа", "kategoriya", "категория/группа", "тоифа", "toifa"},
+}
+
+// headerFieldOrder - bir xil katakka bir nechta maydon mos kelsa, tartib muhim
+var headerFieldOrder = []string{fieldNum, fieldName, fieldCount, fieldPrice, fieldManufacturer, fieldBarcode, fieldSeries, fieldExpiry, fieldCategory}
+
+// columnMap - maydon nomi -> ustun indeksi
+type columnMap map[string]int
+
+// findHeader - birinchi qatorlar ichidan sarlavha qatorini topish
+func findHeader(rows [][]string) (columnMap, int, bool) {
+	for i := 0; i < headerScanRows && i < len(rows); i++ {
+		cols := matchHeader(rows[i])
+		if cols.recognized() {
+			return cols, i, true
+		}
+	}
+	return nil, -1, false
+}
+
+func matchHeader(row []string) columnMap {
+	cols := columnMap{}
+	for idx, cell := range row {
+		cell = normalizeHeader(cell)
+		if cell == "" {
+			continue
+		}
+		for _, field := range headerFieldOrder {
+			if _, taken := cols[field]; taken {
+				continue
+			}
+			if matchesAlias(cell, headerAliases[field]) {
+				cols[field] = idx
+				break
+			}
+		}
+	}
+	return cols
+}
+
+// recognized - kamida nom va narx ustuni bo'lishi kerak: narxsiz fayldagi har bir qator
+// narx 0 bo'lib karantinga tushardi
+func (c columnMap) recognized() bool {
+	if _, ok := c[fieldName]; !ok {
+		return false
+	}
+	_, hasPrice := c[fieldPrice]
+	return hasPrice
+}
+
+func normalizeHeader(s string) string {
+	s = strings.ToLower(strings.TrimSpace(s))
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// matchesAlias - aniq mos kelish yoki "цена (сум)", "кол-во, шт" kabi prefiks
+func matchesAlias(cell string, aliases []string) bool {
+	for _, alias := range aliases {
+		if cell == alias {
+			return true
+		}
+		if strings.HasPrefix(cell, alias) {
+			next := []rune(cell[len(alias):])[0]
+			if !unicode.IsLetter(next) && !unicode.IsDigit(next) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// cell - indeks bo'yicha katak qiymati (ustun bo'lmasa bo'sh)
+func (c columnMap) cell(row []string, field string) string {
+	idx, ok := c[field]
+	if !ok || idx >= len(row) {
+		return ""
+	}
+	return strings.TrimSpace(row[idx])
+}
+
+// parseRow - qatorni sarlavha bo'yicha ustunlardan o'qish
+func (c columnMap) parseRow(row []string, decimal string) (med Medicine, err error) {
+	// "Итого" kabi qatorlarda tartib raqami bo'lmaydi
+	if _, hasNum := c[fieldNum]; hasNum && parseNumberSep(c.cell(row, fieldNum), decimal) == 0 {
+		err = fmt.Errorf("tartib raqami yo'q")
+		return
+	}
+
+	med.Name = c.cell(row, fieldName)
+	if med.Name == "" {
+		err = fmt.Errorf("dori nomi bo'sh")
+		return
+	}
+
+	priceStr := c.cell(row, fieldPrice)
+	countStr := c.cell(row, fieldCount)
+	if priceStr == "" && countStr == "" {
+		err = fmt.Errorf("narx va miqdor ko'rsatilmagan")
+		return
+	}
+
+	med.Count = parseNumberSep(countStr, decimal)
+	med.Price = parsePrice(priceStr, decimal)
+	med.Manufacturer = c.cell(row, fieldManufacturer)
+	med.Barcode = c.cell(row, fieldBarcode)
+	med.Category = c.cell(row, fieldCategory) // bo'sh bo'lsa nomdan aniqlanadi
+	return
+}
+
+// parseBatch - seriya va yaroqlilik muddati ustunlari; ikkalasi ham bo'sh bo'lsa nil.
+// Muddat o'qilmasa partiya muddatsiz qaytadi va xato bilan birga.
+func (c columnMap) parseBatch(row []string, count int) (*Batch, error) {
+	series := c.cell(row, fieldSeries)
+	expiryStr := c.cell(row, fieldExpiry)
+	if series == "" && expiryStr == "" {
+		return nil, nil
+	}
+
+	batch := &Batch{Series: series, Count: count}
+	if expiryStr == "" {
+		return batch, nil
+	}
+	expiry, err := parseExpiry(expiryStr)
+	if err != nil {
+		return batch, fmt.Errorf("yaroqlilik muddati o'qilmadi: %s", expiryStr)
+	}
+	batch.Expiry = expiry
+	return batch, nil
+}
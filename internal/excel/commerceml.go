@@ -0,0 +1,340 @@
+package excel
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+// cmlRoot - CommerceML hujjatining ildiz elementi
+const cmlRoot = "КоммерческаяИнформация"
+
+// IsCommerceMLFile - 1C CommerceML bo'lishi mumkin bo'lgan kengaytmalar (.xml yoki arxiv).
+// Faqat nom tekshiriladi - importer tanlashdan oldin SniffCommerceML bilan tarkib tekshiriladi.
+func IsCommerceMLFile(name string) bool {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".xml", ".zip":
+		return true
+	}
+	return false
+}
+
+// SniffCommerceML - fayl haqiqatan CommerceML ekanini ildiz elementidan aniqlash
+// (arxivda - birorta .xml faylning ildizi). Arxiv ixtiyoriy joydan o'qishni talab qiladi,
+// shuning uchun fayl xotiraga o'qiladi; keyingi parser qaytarilgan reader dan o'qiydi.
+func SniffCommerceML(r io.Reader) (io.Reader, bool, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, false, err
+	}
+	return bytes.NewReader(data), isCommerceML(data), nil
+}
+
+// isCommerceML - XML ildizi yoki arxivdagi .xml fayllardan birining ildizi КоммерческаяИнформация
+func isCommerceML(data []byte) bool {
+	if !bytes.HasPrefix(data, zipMagic) {
+		return xmlRoot(bytes.NewReader(data)) == cmlRoot
+	}
+
+	// .xlsx ham ZIP - uning ichidagi XML larning ildizi boshqa (Types, workbook, ...)
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return false
+	}
+	for _, f := range zr.File {
+		if !strings.EqualFold(path.Ext(f.Name), ".xml") {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			continue
+		}
+		root := xmlRoot(rc)
+		rc.Close()
+		if root == cmlRoot {
+			return true
+		}
+	}
+	return false
+}
+
+// xmlRoot - XML hujjatining ildiz elementi nomi, o'qilmasa bo'sh
+func xmlRoot(r io.Reader) string {
+	dec := newCMLDecoder(r)
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return ""
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			return start.Name.Local
+		}
+	}
+}
+
+// newCMLDecoder - 1C eksportlarida uchraydigan windows-1251 kodirovkasini ham o'qiydigan decoder
+func newCMLDecoder(r io.Reader) *xml.Decoder {
+	dec := xml.NewDecoder(r)
+	dec.CharsetReader = func(label string, input io.Reader) (io.Reader, error) {
+		switch strings.ToLower(label) {
+		case "windows-1251", "cp1251":
+			return charmap.Windows1251.NewDecoder().Reader(input), nil
+		}
+		return nil, fmt.Errorf("kodirovka qo'llab-quvvatlanmaydi: %s", label)
+	}
+	return dec
+}
+
+// cmlProduct - import.xml dagi <Товар>
+type cmlProduct struct {
+	ID           string `xml:"Ид"`
+	Name         string `xml:"Наименование"`
+	Barcode      string `xml:"Штрихкод"`
+	Manufacturer struct {
+		Name string `xml:"Наименование"`
+	} `xml:"Изготовитель"`
+}
+
+// cmlOffer - offers.xml dagi <Предложение>
+type cmlOffer struct {
+	ID      string `xml:"Ид"`
+	Name    string `xml:"Наименование"`
+	Barcode string `xml:"Штрихкод"`
+	Prices  []struct {
+		PerUnit string `xml:"ЦенаЗаЕдиницу"`
+		TypeID  string `xml:"ИдТипаЦены"`
+	} `xml:"Цены>Цена"`
+	Count  string `xml:"Количество"`
+	Stores []struct {
+		Count string `xml:"КоличествоНаСкладе,attr"`
+	} `xml:"Склад"`
+}
+
+// cmlPriceType - <ТипЦены>
+type cmlPriceType struct {
+	ID   string `xml:"Ид"`
+	Name string `xml:"Наименование"`
+}
+
+// commerceML - bir yoki bir nechta XML fayldan yig'ilgan katalog va takliflar
+type commerceML struct {
+	products   map[string]cmlProduct
+	order      []string // mahsulotlar fayldagi tartibda
+	offers     []cmlOffer
+	priceTypes []cmlPriceType
+}
+
+// PrepareCommerceML - CommerceML faylini (import.xml, offers.xml yoki ikkalasi .zip ichida) parse qilish.
+// Natija Excel import bilan bir xil StagedImport - preview, hisobot va Commit o'zgarmaydi.
+func PrepareCommerceML(db *sql.DB, fileName string, fileData io.Reader, phone, address string, pharmacyID int) (*StagedImport, error) {
+	data, err := io.ReadAll(fileData)
+	if err != nil {
+		return nil, err
+	}
+
+	if !isCommerceML(data) {
+		return nil, fmt.Errorf("fayl 1C CommerceML emas (%s elementi topilmadi)", cmlRoot)
+	}
+
+	cml := &commerceML{products: make(map[string]cmlProduct)}
+	if bytes.HasPrefix(data, zipMagic) {
+		err = cml.readZip(data)
+	} else {
+		err = cml.readXML(bytes.NewReader(data))
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(cml.products) == 0 && len(cml.offers) == 0 {
+		return nil, fmt.Errorf("CommerceML faylida tovar yoki taklif topilmadi")
+	}
+
+	// Ustunlar aniq - dorixonaning Excel profili ishlatilmaydi
+	profile := &ImportProfile{
+		HeaderRow:        1,
+		DecimalSeparator: ".",
+		Columns:          cmlColumns,
+	}
+	staged, err := parseStream(fileName, &sliceRows{rows: cml.rows()}, profile, phone, address, pharmacyID)
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(data)
+	staged.Mode = LoadMode(db, pharmacyID)
+	staged.Checksum = hex.EncodeToString(sum[:])
+	return staged, nil
+}
+
+// readZip - 1C almashinuv arxivi: ichidagi barcha .xml fayllar o'qiladi
+func (c *commerceML) readZip(data []byte) error {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return fmt.Errorf("arxiv ochilmadi: %v", err)
+	}
+
+	found := false
+	for _, f := range zr.File {
+		if !strings.EqualFold(path.Ext(f.Name), ".xml") {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return fmt.Errorf("%s ochilmadi: %v", f.Name, err)
+		}
+		err = c.readXML(rc)
+		rc.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %v", f.Name, err)
+		}
+		found = true
+	}
+	if !found {
+		return fmt.Errorf("arxivda .xml fayl yo'q")
+	}
+	return nil
+}
+
+// readXML - oqim bo'yicha o'qish, faqat kerakli elementlar decode qilinadi
+func (c *commerceML) readXML(r io.Reader) error {
+	dec := newCMLDecoder(r)
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("XML o'qilmadi: %v", err)
+		}
+
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch start.Name.Local {
+		case "Товар":
+			var p cmlProduct
+			if err := dec.DecodeElement(&p, &start); err != nil {
+				return fmt.Errorf("tovar o'qilmadi: %v", err)
+			}
+			if _, exists := c.products[p.ID]; !exists {
+				c.order = append(c.order, p.ID)
+			}
+			c.products[p.ID] = p
+		case "Предложение":
+			var o cmlOffer
+			if err := dec.DecodeElement(&o, &start); err != nil {
+				return fmt.Errorf("taklif o'qilmadi: %v", err)
+			}
+			c.offers = append(c.offers, o)
+		case "ТипЦены":
+			var t cmlPriceType
+			if err := dec.DecodeElement(&t, &start); err != nil {
+				return fmt.Errorf("narx turi o'qilmadi: %v", err)
+			}
+			c.priceTypes = append(c.priceTypes, t)
+		}
+	}
+}
+
+// retailPriceType - "Розничная" narx turi ID si (topilmasa bo'sh - taklifning birinchi narxi olinadi)
+func (c *commerceML) retailPriceType() string {
+	for _, t := range c.priceTypes {
+		if strings.Contains(strings.ToLower(t.Name), "розн") {
+			return t.ID
+		}
+	}
+	return ""
+}
+
+// Sintetik jadval ustunlari: parseStream shu ustunlardan o'qiydi.
+// Артикул olinmaydi - medicines jadvalida unga ustun yo'q, dori nom va shtrix-kod bo'yicha moslanadi.
+var cmlColumns = map[string]int{
+	fieldNum:          0,
+	fieldName:         1,
+	fieldCount:        2,
+	fieldPrice:        3,
+	fieldManufacturer: 4,
+	fieldBarcode:      5,
+}
+
+// rows - katalog va takliflarni jadvalga aylantirish.
+// Taklif mahsulotga Ид bo'yicha bog'lanadi ("товар#характеристика" da # gacha qismi).
+func (c *commerceML) rows() [][]string {
+	rows := [][]string{{"№", "Наименование", "Количество", "Цена", "Производитель", "Штрихкод"}}
+	retail := c.retailPriceType()
+
+	offered := make(map[string]bool)
+	for _, o := range c.offers {
+		productID, _, _ := strings.Cut(o.ID, "#")
+		p := c.products[productID]
+		offered[productID] = true
+
+		name := o.Name
+		if name == "" {
+			name = p.Name
+		}
+		barcode := o.Barcode
+		if barcode == "" {
+			barcode = p.Barcode
+		}
+
+		price := ""
+		if len(o.Prices) > 0 {
+			price = o.Prices[0].PerUnit
+		}
+		for _, pr := range o.Prices {
+			if retail != "" && pr.TypeID == retail {
+				price = pr.PerUnit
+				break
+			}
+		}
+
+		count := o.Count
+		if count == "" && len(o.Stores) > 0 {
+			total := 0.0
+			for _, s := range o.Stores {
+				n, _ := strconv.ParseFloat(strings.TrimSpace(s.Count), 64)
+				total += n
+			}
+			count = strconv.FormatFloat(total, 'f', -1, 64)
+		}
+
+		rows = append(rows, []string{strconv.Itoa(len(rows)), name, count, price, p.Manufacturer.Name, barcode})
+	}
+
+	// Taklifsiz mahsulotlar (faqat import.xml) - narx va miqdor yo'q, hisobotda o'tkazilgan bo'ladi
+	for _, id := range c.order {
+		if offered[id] {
+			continue
+		}
+		p := c.products[id]
+		rows = append(rows, []string{strconv.Itoa(len(rows)), p.Name, "", "", p.Manufacturer.Name, p.Barcode})
+	}
+	return rows
+}
+
+// sliceRows - xotiradagi qatorlar uchun rowIterator
+type sliceRows struct {
+	rows [][]string
+	cur  int
+}
+
+func (s *sliceRows) Next() bool {
+	s.cur++
+	return s.cur <= len(s.rows)
+}
+
+func (s *sliceRows) Columns() ([]string, error) { return s.rows[s.cur-1], nil }
+func (s *sliceRows) Err() error                 { return nil }
+func (s *sliceRows) Close() error               { return nil }
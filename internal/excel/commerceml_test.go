@@ -0,0 +1,64 @@
+package excel
+
+import (
+	"archive/zip"
+	"bytes"
+	"testing"
+
+	"github.com/xuri/excelize/v2"
+	"golang.org/x/text/encoding/charmap"
+)
+
+func zipOf(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestIsCommerceML(t *testing.T) {
+	const cml = `<?xml version="1.0" encoding="UTF-8"?>
+<КоммерческаяИнформация ВерсияСхемы="2.05"><Каталог/></КоммерческаяИнформация>`
+
+	cp1251, err := charmap.Windows1251.NewEncoder().String(
+		`<?xml version="1.0" encoding="windows-1251"?><КоммерческаяИнформация/>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := excelize.NewFile()
+	xlsx, err := f.WriteToBuffer()
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+		want bool
+	}{
+		{"xml", []byte(cml), true},
+		{"windows-1251", []byte(cp1251), true},
+		{"boshqa XML", []byte(`<?xml version="1.0"?><price><item/></price>`), false},
+		{"XML emas", []byte("Наименование;Цена\nАнальгин;1200"), false},
+		{"1C arxivi", zipOf(t, map[string]string{"import.xml": cml, "readme.txt": "x"}), true},
+		{"arxivda CommerceML yo'q", zipOf(t, map[string]string{"data.xml": "<root/>"}), false},
+		{"xlsx", xlsx.Bytes(), false},
+	}
+	for _, tt := range tests {
+		if got := isCommerceML(tt.data); got != tt.want {
+			t.Errorf("%s: isCommerceML = %v; kutilgan %v", tt.name, got, tt.want)
+		}
+	}
+}
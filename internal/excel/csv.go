@@ -0,0 +1,165 @@
+package excel
+
+import (
+	"bufio"
+	"bytes"
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/transform"
+)
+
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// IsCSVFile - kassa dasturlaridan kelgan matnli eksport fayllarini aniqlash
+func IsCSVFile(fileName string) bool {
+	name := strings.ToLower(fileName)
+	return strings.HasSuffix(name, ".csv") ||
+		strings.HasSuffix(name, ".tsv") ||
+		strings.HasSuffix(name, ".txt")
+}
+
+// UploadCSV - CSV/TSV faylni UploadExcel bilan bir xil pipeline orqali yuklash
+func UploadCSV(db *sql.DB, fileName string, fileData io.Reader, phone, address string, pharmacyID int, reporter Reporter) (*ImportResult, error) {
+	staged, err := PrepareCSV(db, fileName, fileData, phone, address, pharmacyID)
+	if err != nil {
+		return nil, err
+	}
+	return staged.Commit(db, reporter)
+}
+
+// PrepareCSV - CSV faylni o'qib parse qilish, bazaga yozmasdan
+func PrepareCSV(db *sql.DB, fileName string, fileData io.Reader, phone, address string, pharmacyID int) (*StagedImport, error) {
+	// CSV da sheet yo'q, profilning qolgan sozlamalari amal qiladi
+	profile, err := LoadProfile(db, pharmacyID)
+	if err != nil {
+		return nil, err
+	}
+
+	hashed, sum := checksumReader(fileData)
+	it, err := openCSVRows(hashed)
+	if err != nil {
+		return nil, err
+	}
+	defer it.Close()
+
+	staged, err := parseStream(fileName, it, profile, phone, address, pharmacyID)
+	if err != nil {
+		return nil, err
+	}
+	staged.Mode = LoadMode(db, pharmacyID)
+	staged.Checksum = sum()
+	return staged, nil
+}
+
+// csvSniffSize - kodirovka va ajratuvchi shu hajmdagi boshlang'ich qismdan aniqlanadi
+const csvSniffSize = 64 * 1024
+
+// csvRows - CSV qatorlarini oqim bo'yicha o'qish
+type csvRows struct {
+	r   *csv.Reader
+	row []string
+	err error
+}
+
+func openCSVRows(r io.Reader) (rowIterator, error) {
+	br := bufio.NewReaderSize(r, csvSniffSize)
+	head, err := br.Peek(csvSniffSize)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, fmt.Errorf("fayl o'qilmadi: %v", err)
+	}
+	if bytes.HasPrefix(head, utf8BOM) {
+		br.Discard(len(utf8BOM))
+		head = head[len(utf8BOM):]
+	}
+
+	// UTF-8 bo'lmasa Windows-1251 deb hisoblanadi
+	var text io.Reader = br
+	sample := string(head)
+	if !validUTF8Prefix(head) {
+		decoder := charmap.Windows1251.NewDecoder()
+		decoded, err := decoder.Bytes(head)
+		if err != nil {
+			return nil, fmt.Errorf("fayl kodirovkasi o'qilmadi: %v", err)
+		}
+		sample = string(decoded)
+		text = transform.NewReader(br, charmap.Windows1251.NewDecoder())
+	}
+
+	cr := csv.NewReader(text)
+	cr.Comma = detectDelimiter(sample)
+	cr.FieldsPerRecord = -1
+	cr.LazyQuotes = true
+	cr.TrimLeadingSpace = true
+
+	return &csvRows{r: cr}, nil
+}
+
+func (c *csvRows) Next() bool {
+	if c.err != nil {
+		return false
+	}
+	c.row, c.err = c.r.Read()
+	return c.err == nil
+}
+
+func (c *csvRows) Columns() ([]string, error) { return c.row, nil }
+func (c *csvRows) Close() error               { return nil }
+
+func (c *csvRows) Err() error {
+	if c.err == io.EOF {
+		return nil
+	}
+	return c.err
+}
+
+// validUTF8Prefix - buferning oxirida bo'lingan ko'p baytli belgi bo'lishi mumkin
+func validUTF8Prefix(b []byte) bool {
+	for cut := 0; cut < utf8.UTFMax && cut <= len(b); cut++ {
+		if utf8.Valid(b[:len(b)-cut]) {
+			return true
+		}
+	}
+	return false
+}
+
+// detectDelimiter - birinchi qatorlar bo'yicha eng ko'p uchraydigan ajratuvchini tanlash
+func detectDelimiter(text string) rune {
+	lines := strings.SplitN(text, "\n", 11)
+	if len(lines) > 10 {
+		lines = lines[:10]
+	}
+
+	best := ';'
+	bestCount := 0
+	for _, delim := range []rune{';', '\t', ','} {
+		count := 0
+		for _, line := range lines {
+			count += countOutsideQuotes(line, delim)
+		}
+		if count > bestCount {
+			best = delim
+			bestCount = count
+		}
+	}
+	return best
+}
+
+func countOutsideQuotes(line string, delim rune) int {
+	count := 0
+	inQuotes := false
+	for _, ch := range line {
+		switch {
+		case ch == '"':
+			inQuotes = !inQuotes
+		case ch == delim && !inQuotes:
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,267 @@
+package excel
+
+import (
+	"fmt"
+	"html"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// O'zgarish turlari (diff hisoboti uchun)
+const (
+	ChangeNew       = "new"        // bazada yo'q edi
+	ChangeMissing   = "missing"    // bazada bor, faylda yo'q
+	ChangePriceUp   = "price_up"   // narx oshdi
+	ChangePriceDown = "price_down" // narx tushdi
+	ChangeStock     = "stock"      // faqat miqdor o'zgardi
+)
+
+var changeLabels = map[string]string{
+	ChangeNew:       "Yangi",
+	ChangeMissing:   "Faylda yo'q",
+	ChangePriceUp:   "Narx oshdi",
+	ChangePriceDown: "Narx tushdi",
+	ChangeStock:     "Miqdor o'zgardi",
+}
+
+// diffTopSize - Telegram xabarida ko'rsatiladigan eng katta o'zgarishlar
+const diffTopSize = 5
+
+// ImportDiff - import oldidagi va keyingi holat farqi
+type ImportDiff struct {
+	New       []MedicineChange
+	Missing   []MedicineChange // replace rejimida o'chirilgan, merge rejimida bazada qolgan
+	Changed   []MedicineChange // narx yoki miqdor o'zgargan
+	Unchanged int
+}
+
+// PricePercent - narx o'zgarishi foizda (eski narx 0 bo'lsa ok=false)
+func (c MedicineChange) PricePercent() (float64, bool) {
+	if c.OldPrice <= 0 {
+		return 0, false
+	}
+	return float64(c.NewPrice-c.OldPrice) * 100 / float64(c.OldPrice), true
+}
+
+// computeDiff - upsert oldidagi dorilar bilan fayldagi dorilarni solishtirish
+func computeDiff(existing map[string]Medicine, medicines []Medicine) *ImportDiff {
+	diff := &ImportDiff{}
+	inFile := make(map[string]bool, len(medicines))
+
+	for _, med := range medicines {
+		inFile[med.Name] = true
+		old, ok := existing[med.Name]
+		if !ok {
+			diff.New = append(diff.New, MedicineChange{Kind: ChangeNew, Name: med.Name, NewPrice: med.Price, NewCount: med.Count})
+			continue
+		}
+
+		change := MedicineChange{
+			Name:     med.Name,
+			OldPrice: old.Price,
+			NewPrice: med.Price,
+			OldCount: old.Count,
+			NewCount: med.Count,
+		}
+		switch {
+		case med.Price > old.Price:
+			change.Kind = ChangePriceUp
+		case med.Price < old.Price:
+			change.Kind = ChangePriceDown
+		case med.Count != old.Count:
+			change.Kind = ChangeStock
+		default:
+			diff.Unchanged++
+			continue
+		}
+		diff.Changed = append(diff.Changed, change)
+	}
+
+	for name, old := range existing {
+		if !inFile[name] {
+			diff.Missing = append(diff.Missing, MedicineChange{Kind: ChangeMissing, Name: name, OldPrice: old.Price, OldCount: old.Count})
+		}
+	}
+
+	sort.Slice(diff.New, func(i, j int) bool { return diff.New[i].Name < diff.New[j].Name })
+	sort.Slice(diff.Missing, func(i, j int) bool { return diff.Missing[i].Name < diff.Missing[j].Name })
+	// Avval narxi eng ko'p (foizda) o'zgarganlar, keyin miqdori eng ko'p o'zgarganlar
+	sort.SliceStable(diff.Changed, func(i, j int) bool {
+		a, b := diff.Changed[i], diff.Changed[j]
+		pa, pb := priceWeight(a), priceWeight(b)
+		if pa != pb {
+			return pa > pb
+		}
+		return absInt(a.NewCount-a.OldCount) > absInt(b.NewCount-b.OldCount)
+	})
+	return diff
+}
+
+// priceWeight - saralash uchun narx o'zgarishi (eski narx 0 bo'lsa eng yuqorida)
+func priceWeight(c MedicineChange) float64 {
+	if c.NewPrice == c.OldPrice {
+		return 0
+	}
+	pct, ok := c.PricePercent()
+	if !ok {
+		return math.Inf(1)
+	}
+	return math.Abs(pct)
+}
+
+// Count - berilgan turdagi o'zgarishlar soni
+func (d *ImportDiff) Count(kind string) int {
+	switch kind {
+	case ChangeNew:
+		return len(d.New)
+	case ChangeMissing:
+		return len(d.Missing)
+	}
+	n := 0
+	for _, c := range d.Changed {
+		if c.Kind == kind {
+			n++
+		}
+	}
+	return n
+}
+
+// StockChanged - miqdori o'zgargan dorilar (narxi ham o'zgarganlari bilan)
+func (d *ImportDiff) StockChanged() int {
+	n := 0
+	for _, c := range d.Changed {
+		if c.NewCount != c.OldCount {
+			n++
+		}
+	}
+	return n
+}
+
+// Empty - hech narsa o'zgarmadi
+func (d *ImportDiff) Empty() bool {
+	return len(d.New) == 0 && len(d.Missing) == 0 && len(d.Changed) == 0
+}
+
+// Summary - Telegram xabari uchun qisqa hisobot (HTML). replace - faylda yo'qlar o'chirildimi.
+func (d *ImportDiff) Summary(replace bool) string {
+	if d.Empty() {
+		return "🟰 Narx va miqdorlar o'zgarmadi"
+	}
+
+	var b strings.Builder
+	b.WriteString("<b>O'zgarishlar:</b>\n")
+	b.WriteString(fmt.Sprintf("🆕 Yangi: <b>%d</b>\n", len(d.New)))
+	if replace {
+		b.WriteString(fmt.Sprintf("➖ Yo'qolgan (o'chirildi): <b>%d</b>\n", len(d.Missing)))
+	} else {
+		b.WriteString(fmt.Sprintf("➖ Faylda yo'q (bazada qoldi): <b>%d</b>\n", len(d.Missing)))
+	}
+	b.WriteString(fmt.Sprintf("📈 Narx oshdi: <b>%d</b>\n", d.Count(ChangePriceUp)))
+	b.WriteString(fmt.Sprintf("📉 Narx tushdi: <b>%d</b>\n", d.Count(ChangePriceDown)))
+	b.WriteString(fmt.Sprintf("📦 Miqdor o'zgardi: <b>%d</b>\n", d.StockChanged()))
+	b.WriteString(fmt.Sprintf("🟰 O'zgarmadi: <b>%d</b>", d.Unchanged))
+
+	var top []string
+	for _, c := range d.Changed {
+		if len(top) == diffTopSize {
+			break
+		}
+		top = append(top, "• "+html.EscapeString(c.Name)+": "+c.describe())
+	}
+	if len(top) > 0 {
+		b.WriteString("\n\n<b>Eng katta o'zgarishlar:</b>\n")
+		b.WriteString(strings.Join(top, "\n"))
+	}
+	return b.String()
+}
+
+// describe - "12 000,00 → 13 200,00 (+10,0%), miqdor 5 → 12"
+func (c MedicineChange) describe() string {
+	var parts []string
+	if c.NewPrice != c.OldPrice {
+		part := c.OldPrice.Format() + " → " + c.NewPrice.Format()
+		if pct, ok := c.PricePercent(); ok {
+			part += " (" + formatPercent(pct) + ")"
+		}
+		parts = append(parts, part)
+	}
+	if c.NewCount != c.OldCount {
+		parts = append(parts, fmt.Sprintf("miqdor %d → %d", c.OldCount, c.NewCount))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// formatPercent - "+10,0%" yoki "-3,5%"
+func formatPercent(pct float64) string {
+	return strings.Replace(fmt.Sprintf("%+.1f%%", pct), ".", ",", 1)
+}
+
+// Report - barcha o'zgarishlar .xlsx ko'rinishida
+func (d *ImportDiff) Report() ([]byte, error) {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	sheet := "O'zgarishlar"
+	f.SetSheetName(f.GetSheetName(0), sheet)
+
+	headers := []interface{}{"Holat", "Dori nomi", "Eski narx", "Yangi narx", "Narx %", "Eski miqdor", "Yangi miqdor", "Miqdor farqi"}
+	if err := f.SetSheetRow(sheet, "A1", &headers); err != nil {
+		return nil, err
+	}
+
+	bold, err := f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}})
+	if err != nil {
+		return nil, err
+	}
+	priceFormat := "#,##0.00"
+	priceStyle, err := f.NewStyle(&excelize.Style{CustomNumFmt: &priceFormat})
+	if err != nil {
+		return nil, err
+	}
+	f.SetCellStyle(sheet, "A1", "H1", bold)
+	f.SetColWidth(sheet, "A", "A", 16)
+	f.SetColWidth(sheet, "B", "B", 45)
+	f.SetColWidth(sheet, "C", "H", 13)
+	f.SetPanes(sheet, &excelize.Panes{Freeze: true, YSplit: 1, TopLeftCell: "A2", ActivePane: "bottomLeft"})
+
+	var all []MedicineChange
+	all = append(all, d.Changed...)
+	all = append(all, d.New...)
+	all = append(all, d.Missing...)
+
+	for i, c := range all {
+		percent := interface{}("")
+		if pct, ok := c.PricePercent(); ok && c.Kind != ChangeMissing {
+			percent = math.Round(pct*10) / 10
+		}
+		row := []interface{}{
+			changeLabels[c.Kind], c.Name,
+			float64(c.OldPrice) / 100, float64(c.NewPrice) / 100, percent,
+			c.OldCount, c.NewCount, c.NewCount - c.OldCount,
+		}
+		if err := f.SetSheetRow(sheet, fmt.Sprintf("A%d", i+2), &row); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(all) > 0 {
+		f.SetCellStyle(sheet, "C2", fmt.Sprintf("D%d", len(all)+1), priceStyle)
+		f.AutoFilter(sheet, fmt.Sprintf("A1:H%d", len(all)+1), nil)
+	}
+
+	buf, err := f.WriteToBuffer()
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func absInt(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
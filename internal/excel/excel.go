@@ -1,30 +1,39 @@
 package excel
 
 import (
-	"bytes"
 	"database/sql"
-	"encoding/json"
 	"fmt"
 	"io"
-	"net/http"
-	"os"
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
-	"github.com/xuri/excelize/v2"
+	"github.com/lib/pq"
+
+	"testuchun/internal/money"
 )
 
 func parseNumber(s string) int {
-	s = strings.TrimSpace(s)
-	s = strings.ReplaceAll(s, " ", "")
-	s = strings.ReplaceAll(s, ",", ".")
-	
-	f, err := strconv.ParseFloat(s, 64)
+	return parseNumberSep(s, "")
+}
+
+// parseNumberSep - butun son (miqdor, tartib raqami); kasr qism tashlanadi
+func parseNumberSep(s, decimal string) int {
+	m, err := money.Parse(s, decimal)
+	if err != nil {
+		return 0
+	}
+	return int(m.Som())
+}
+
+// parsePrice - narxni tiyingacha aniq o'qish
+func parsePrice(s, decimal string) money.Money {
+	m, err := money.Parse(s, decimal)
 	if err != nil {
 		return 0
 	}
-	return int(f)
+	return m
 }
 
 // detectCategory - dori nomidan kategoriyani aniqlash
@@ -81,7 +90,7 @@ func detectDescription(medicineName, category string) string {
 	return "Shifo-dori vositasi"
 }
 
-func parseRowData(rowStr string) (num int, name string, count, price int, manufacturer string, ok bool) {
+func parseRowData(rowStr string) (num int, name string, count int, price money.Money, manufacturer string, ok bool) {
 	rowStr = strings.TrimSpace(rowStr)
 	if rowStr == "" {
 		return
@@ -94,7 +103,7 @@ func parseRowData(rowStr string) (num int, name string, count, price int, manufa
 		num, _ = strconv.Atoi(matches[1])
 		name = strings.TrimSpace(matches[2])
 		count = parseNumber(matches[3])
-		price = parseNumber(matches[4])
+		price = parsePrice(matches[4], "")
 		manufacturer = strings.TrimSpace(matches[5])
 		ok = true
 		return
@@ -107,7 +116,7 @@ func parseRowData(rowStr string) (num int, name string, count, price int, manufa
 		num, _ = strconv.Atoi(matches[1])
 		name = strings.TrimSpace(matches[2])
 		count = parseNumber(matches[3])
-		price = parseNumber(matches[4])
+		price = parsePrice(matches[4], "")
 		manufacturer = strings.TrimSpace(matches[5])
 		ok = true
 		return
@@ -116,40 +125,14 @@ func parseRowData(rowStr string) (num int, name string, count, price int, manufa
 	return
 }
 
-func sendTelegramMessage(botToken, chatID, message string) error {
-	url := fmt.Sprintf("https://api.telegram.org/bot%s/sendMessage", botToken)
-	
-	payload := map[string]interface{}{
-		"chat_id": chatID,
-		"text":    message,
-		"parse_mode": "HTML",
-	}
-	
-	jsonData, err := json.Marshal(payload)
-	if err != nil {
-		return err
-	}
-	
-	resp, err := http.Post(url, "application/json", bytes.NewBuffer(jsonData))
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-	
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("telegram API error: %s", string(body))
-	}
-	
-	return nil
-}
-
 // Medicine struct - dorilarni saqlash uchun
 type Medicine struct {
 	Name         string
-	Price        int
+	Price        money.Money
 	Count        int
 	Manufacturer string
+	Barcode      string // EAN-13 yoki EAN-8, bo'lmasa bo'sh
+	Batches      []Batch // seriya/muddat ustunlari bo'lsa; Count - ularning yig'indisi
 	Phone        string
 	Address      string
 	Description  string
@@ -157,159 +140,294 @@ type Medicine struct {
 	PharmacyID   int
 }
 
-func UploadExcel(db *sql.DB, fileName string, fileData io.Reader, botToken, chatID, phone, address string, pharmacyID int) error {
-	tmp := "upload_tmp.xlsx"
-	
-	out, err := os.Create(tmp)
+// UploadExcel - Excel faylni tasdiqsiz parse qilib bazaga yozish. reporter nil bo'lishi mumkin.
+func UploadExcel(db *sql.DB, fileName string, fileData io.Reader, phone, address string, pharmacyID int, reporter Reporter) (*ImportResult, error) {
+	staged, err := PrepareExcel(db, fileName, fileData, phone, address, pharmacyID)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	_, err = io.Copy(out, fileData)
-	out.Close()
-	if err != nil {
-		return err
-	}
-	defer os.Remove(tmp)
+	return staged.Commit(db, reporter)
+}
 
-	f, err := excelize.OpenFile(tmp)
+// PrepareExcel - Excel faylni o'qib parse qilish, bazaga yozmasdan
+func PrepareExcel(db *sql.DB, fileName string, fileData io.Reader, phone, address string, pharmacyID int) (*StagedImport, error) {
+	// Dorixonaning saqlangan import profili (bo'lmasa avtomatik aniqlash)
+	profile, err := LoadProfile(db, pharmacyID)
 	if err != nil {
-		return fmt.Errorf("fayl ochilmadi: %v", err)
+		return nil, err
 	}
-	defer f.Close()
 
-	sheetName := f.GetSheetName(0)
-	if sheetName == "" {
-		return fmt.Errorf("sheet topilmadi")
-	}
+	return prepareExcel(db, fileName, fileData, profile, phone, address, pharmacyID)
+}
 
-	rows, err := f.GetRows(sheetName)
+func prepareExcel(db *sql.DB, fileName string, fileData io.Reader, profile *ImportProfile, phone, address string, pharmacyID int) (*StagedImport, error) {
+	// .xlsx yoki .xls - format fayl tarkibidan aniqlanadi
+	hashed, sum := checksumReader(fileData)
+	it, err := openRows(hashed, profile.sheet())
 	if err != nil {
-		return fmt.Errorf("qatorlar o'qilmadi: %v", err)
+		return nil, err
 	}
+	defer it.Close()
 
-	fmt.Println("📊 Jami qatorlar:", len(rows))
+	staged, err := parseStream(fileName, it, profile, phone, address, pharmacyID)
+	if err != nil {
+		return nil, err
+	}
+	staged.Mode = LoadMode(db, pharmacyID)
+	staged.Checksum = sum()
+	return staged, nil
+}
 
-	var rawRows []string
-	for _, row := range rows {
-		fullRow := strings.Join(row, " ")
-		fullRow = strings.TrimSpace(fullRow)
-		if fullRow != "" {
-			rawRows = append(rawRows, fullRow)
+// parseStream - qatorlarni oqim bo'yicha parse qilish (Excel va CSV uchun umumiy)
+func parseStream(fileName string, it rowIterator, profile *ImportProfile, phone, address string, pharmacyID int) (*StagedImport, error) {
+	// Sarlavha qidirish uchun faqat birinchi qatorlar buferlanadi, qolganlari oqim bo'yicha o'qiladi
+	bufSize := headerScanRows
+	if profile != nil && profile.HeaderRow > bufSize {
+		bufSize = profile.HeaderRow
+	}
+	var head [][]string
+	for len(head) < bufSize && it.Next() {
+		row, err := it.Columns()
+		if err != nil {
+			return nil, fmt.Errorf("qatorlar o'qilmadi: %v", err)
 		}
+		head = append(head, row)
+	}
+
+	// Profil yoki sarlavha bo'yicha ustunlar indeks bilan o'qiladi, aks holda regex parser
+	cols, headerRow, hasHeader := resolveColumns(head, profile)
+	skipRows := profile.skipSet()
+	decimal := profile.decimal()
+	if hasHeader {
+		fmt.Printf("📋 Ustunlar: %v (sarlavha: %d-qator)\n", cols, headerRow+1)
+	} else {
+		fmt.Println("⚠️ Sarlavha topilmadi, regex parser ishlatiladi")
 	}
 
 	fmt.Println("\n🔍 Birinchi 10 qator:")
-	for i := 0; i < 10 && i < len(rawRows); i++ {
-		fmt.Printf("Qator %d: %s\n", i+1, rawRows[i])
+	for i, printed := 0, 0; printed < 10 && i < len(head); i++ {
+		fullRow := strings.TrimSpace(strings.Join(head[i], " "))
+		if fullRow != "" {
+			fmt.Printf("Qator %d: %s\n", i+1, fullRow)
+			printed++
+		}
 	}
 
-	// Parse qilingan dorilarni yig'ish (map orqali dublikatlarni oldini olish)
-	medicinesMap := make(map[string]Medicine) // key = dori nomi
+	// Parse qilingan dorilar fayldagi tartibda; dublikatda oxirgi qiymat o'rniga yoziladi
+	var medicines []Medicine
+	indexOf := make(map[string]int) // dori nomi -> medicines dagi indeks
+	rowOf := make(map[string]int)   // dori nomi -> oxirgi qator raqami
+	batchRowOf := make(map[string]int) // dori nomi + partiya -> oxirgi qator raqami
+	now := time.Now()
 	skipped := 0
 	duplicates := 0
-	failedRows := []string{}
+	issues := []RowIssue{}
 	categoryStats := make(map[string]int)
 
-	for i, rowStr := range rawRows {
-		num, name, count, price, mfr, ok := parseRowData(rowStr)
-		
-		if !ok || num == 0 {
-			skipped++
-			if len(failedRows) < 10 {
-				failedRows = append(failedRows, fmt.Sprintf("Qator %d: %s", i+1, rowStr))
+	total := 0
+	for i := 0; ; i++ {
+		var row []string
+		if i < len(head) {
+			row = head[i]
+		} else if it.Next() {
+			var err error
+			if row, err = it.Columns(); err != nil {
+				return nil, fmt.Errorf("qatorlar o'qilmadi: %v", err)
 			}
+		} else {
+			break
+		}
+		total++
+
+		rowStr := strings.TrimSpace(strings.Join(row, " "))
+		if rowStr == "" || (hasHeader && i <= headerRow) || skipRows[i] {
+			continue
+		}
+
+		var med Medicine
+		var parseErr error
+
+		var batch *Batch
+		var batchErr error
+
+		if hasHeader {
+			med, parseErr = cols.parseRow(row, decimal)
+			if parseErr == nil {
+				batch, batchErr = cols.parseBatch(row, med.Count)
+			}
+		} else {
+			var num int
+			var ok bool
+			num, med.Name, med.Count, med.Price, med.Manufacturer, ok = parseRowData(rowStr)
+			if !ok {
+				parseErr = fmt.Errorf("qator formati tanilmadi")
+			} else if num == 0 {
+				parseErr = fmt.Errorf("tartib raqami yo'q")
+			}
+		}
+
+		if parseErr != nil {
+			skipped++
+			issues = append(issues, RowIssue{Row: i + 1, Kind: IssueSkipped, Reason: parseErr.Error(), Content: rowStr})
 			continue
 		}
+
+		if med.Price <= 0 {
+			issues = append(issues, RowIssue{Row: i + 1, Kind: IssueSuspicious, Reason: "narx 0 yoki ko'rsatilmagan, karantinga tushadi", Content: rowStr})
+		}
+		if med.Count < 0 {
+			issues = append(issues, RowIssue{Row: i + 1, Kind: IssueSuspicious, Reason: "miqdor manfiy, karantinga tushadi", Content: rowStr})
+		}
+		if batchErr != nil {
+			issues = append(issues, RowIssue{Row: i + 1, Kind: IssueSuspicious, Reason: batchErr.Error(), Content: rowStr})
+		}
+		if batch != nil && batch.Expired(now) {
+			issues = append(issues, RowIssue{Row: i + 1, Kind: IssueSuspicious, Reason: "partiya muddati o'tgan, qidiruvda ko'rsatilmaydi", Content: rowStr})
+		}
+		if med.Barcode != "" {
+			code, ok := NormalizeBarcode(med.Barcode)
+			if !ok {
+				issues = append(issues, RowIssue{Row: i + 1, Kind: IssueSuspicious, Reason: "shtrix-kod noto'g'ri, saqlanmadi", Content: rowStr})
+			}
+			med.Barcode = code
+		}
 		
-		if mfr == "" {
-			mfr = "Unknown"
+		if med.Manufacturer == "" {
+			med.Manufacturer = "Unknown"
 		}
 
-		category := detectCategory(name)
-		description := detectDescription(name, category)
-		categoryStats[category]++
+		name := med.Name
+		if med.Category == "" {
+			med.Category = detectCategory(name)
+		}
+		med.Description = detectDescription(name, med.Category)
+		med.Phone = phone
+		med.Address = address
+		med.PharmacyID = pharmacyID
+		categoryStats[med.Category]++
+
+		// Bir dorining turli partiyalari alohida qatorlarda keladi - miqdor yig'iladi
+		if batch != nil {
+			med.Batches = []Batch{*batch}
+			batchKey := name + "|" + batch.key()
+			if idx, exists := indexOf[name]; exists && len(medicines[idx].Batches) > 0 {
+				if prev, dup := batchRowOf[batchKey]; dup {
+					duplicates++
+					issues = append(issues, RowIssue{
+						Row:     i + 1,
+						Kind:    IssueDuplicate,
+						Reason:  fmt.Sprintf("%d-qatordagi partiya takrorlandi, oxirgi qiymat olindi", prev),
+						Content: rowStr,
+					})
+				}
+				batchRowOf[batchKey] = i + 1
+				rowOf[name] = i + 1
+				mergeBatch(&medicines[idx], med)
+				continue
+			}
+			batchRowOf[batchKey] = i + 1
+		}
 
 		// Agar bu dori allaqachon mavjud bo'lsa, oxirgi qiymatni saqlash
-		if _, exists := medicinesMap[name]; exists {
+		if prev, exists := rowOf[name]; exists {
 			duplicates++
+			issues = append(issues, RowIssue{
+				Row:     i + 1,
+				Kind:    IssueDuplicate,
+				Reason:  fmt.Sprintf("%d-qatordagi nom takrorlandi, oxirgi qiymat olindi", prev),
+				Content: rowStr,
+			})
 		}
+		rowOf[name] = i + 1
 
-		medicinesMap[name] = Medicine{
-			Name:         name,
-			Price:        price,
-			Count:        count,
-			Manufacturer: mfr,
-			Phone:        phone,
-			Address:      address,
-			Description:  description,
-			Category:     category,
-			PharmacyID:   pharmacyID,
+		if idx, exists := indexOf[name]; exists {
+			medicines[idx] = med
+		} else {
+			indexOf[name] = len(medicines)
+			medicines = append(medicines, med)
 		}
 	}
-
-	// Map'dan slice ga o'tkazish
-	var medicines []Medicine
-	for _, med := range medicinesMap {
-		medicines = append(medicines, med)
+	if err := it.Err(); err != nil {
+		return nil, fmt.Errorf("qatorlar o'qilmadi: %v", err)
 	}
 
+	fmt.Println("\n📊 Jami qatorlar:", total)
 	fmt.Printf("\n✅ Parse qilindi: %d ta dori\n", len(medicines))
 	if duplicates > 0 {
 		fmt.Printf("🔄 Dublikatlar o'chirildi: %d ta (oxirgi qiymat saqlandi)\n", duplicates)
 	}
 	fmt.Printf("⏭️  O'tkazildi: %d ta\n", skipped)
 
+	return &StagedImport{
+		FileName:      fileName,
+		PharmacyID:    pharmacyID,
+		Medicines:     medicines,
+		Skipped:       skipped,
+		Duplicates:    duplicates,
+		Issues:        issues,
+		CategoryStats: categoryStats,
+		CreatedAt:     time.Now(),
+	}, nil
+}
+
+// Commit - parse qilingan dorilarni bazaga yozish. Natija xabarini chaqiruvchi yuboradi;
+// reporter (nil bo'lishi mumkin) saqlash jarayoni haqida xabar oladi.
+func (s *StagedImport) Commit(db *sql.DB, reporter Reporter) (*ImportResult, error) {
+	start := time.Now()
+	res := &ImportResult{
+		PharmacyID:    s.PharmacyID,
+		FileName:      s.FileName,
+		Mode:          s.Mode,
+		Duplicates:    s.Duplicates,
+		Skipped:       s.Skipped,
+		Issues:        s.Issues,
+		CategoryStats: s.CategoryStats,
+	}
+
 	// BATCH INSERT - barcha dorilarni bir vaqtda yuklash
-	if len(medicines) > 0 {
-		saved, err := batchInsertMedicines(db, medicines)
-		if err != nil {
-			return fmt.Errorf("batch insert xato: %v", err)
+	if len(s.Medicines) > 0 {
+		if err := batchInsertMedicines(db, s, res, reporter); err != nil {
+			return nil, fmt.Errorf("batch insert xato: %v", err)
 		}
 
 		fmt.Printf("\n📈 NATIJA:\n")
-		fmt.Printf("✅ Saqlandi: %d ta\n", saved)
+		fmt.Printf("✅ Saqlandi: %d ta (yangi: %d, yangilangan: %d)\n", res.Saved, res.Inserted, res.Updated)
+		if res.Removed > 0 {
+			fmt.Printf("🗑 O'chirildi: %d ta (faylda yo'q)\n", res.Removed)
+		}
+		if len(res.Quarantined) > 0 {
+			fmt.Printf("🚧 Karantinda: %d ta\n", len(res.Quarantined))
+		}
 		
 		fmt.Println("\n📊 Kategoriyalar bo'yicha:")
-		for cat, count := range categoryStats {
+		for cat, count := range s.CategoryStats {
 			fmt.Printf("  %s: %d ta\n", cat, count)
 		}
 		
-		if len(failedRows) > 0 {
-			fmt.Println("\n🔍 Parse qilinmagan qatorlar:")
-			for _, row := range failedRows {
-				fmt.Println(row)
-			}
-		}
-
-		// Telegram xabar
-		if botToken != "" && chatID != "" {
-			message := fmt.Sprintf(
-				"📊 <b>Excel fayl yuklandi</b>\n\n"+
-				"✅ Saqlandi: <b>%d</b> ta dori\n"+
-				"📁 Fayl: <code>%s</code>",
-				saved, fileName,
-			)
-			
-			if duplicates > 0 {
-				message += fmt.Sprintf("\n🔄 Dublikatlar: <b>%d</b> ta (oxirgi qiymat saqlandi)", duplicates)
-			}
-			
-			err := sendTelegramMessage(botToken, chatID, message)
-			if err != nil {
-				fmt.Printf("⚠️ Telegram xabar yuborilmadi: %v\n", err)
-			} else {
-				fmt.Println("✅ Telegram botga xabar yuborildi")
+		if len(s.Issues) > 0 {
+			fmt.Println("\n🔍 Muammoli qatorlar:")
+			for i, issue := range s.Issues {
+				if i == 10 {
+					fmt.Printf("... va yana %d ta\n", len(s.Issues)-10)
+					break
+				}
+				fmt.Printf("Qator %d (%s): %s\n", issue.Row, issue.Reason, issue.Content)
 			}
 		}
 	}
 
-	return nil
+	res.Duration = time.Since(start)
+	return res, nil
 }
 
-// batchInsertMedicines - barcha dorilarni bir query bilan saqlash
-func batchInsertMedicines(db *sql.DB, medicines []Medicine) (int, error) {
+// batchInsertMedicines - barcha dorilarni bir query bilan saqlash.
+// Replace rejimida o'sha transaction ichida faylda yo'q dorilar o'chiriladi.
+// Import tarixi va o'zgarishlar (rollback uchun) ham shu transaction ichida yoziladi.
+func batchInsertMedicines(db *sql.DB, s *StagedImport, res *ImportResult, reporter Reporter) error {
+	medicines := s.Medicines
+	replace := s.Mode == ModeReplace
 	if len(medicines) == 0 {
-		return 0, nil
+		return nil
 	}
 
 	fmt.Println("\n🚀 Batch insert boshlandi...")
@@ -317,10 +435,44 @@ func batchInsertMedicines(db *sql.DB, medicines []Medicine) (int, error) {
 	// Transaction boshlash
 	tx, err := db.Begin()
 	if err != nil {
-		return 0, fmt.Errorf("transaction boshlanmadi: %v", err)
+		return fmt.Errorf("transaction boshlanmadi: %v", err)
 	}
 	defer tx.Rollback()
 
+	// Upsert oldidagi holat - anomaliyalarni tekshirish va diff hisoboti uchun
+	existing, err := loadExisting(tx, s.PharmacyID)
+	if err != nil {
+		return fmt.Errorf("mavjud dorilar o'qilmadi: %v", err)
+	}
+
+	// Shubhali dorilar medicines ga yozilmaydi - admin tasdiqlashini kutadi
+	medicines, flagged := splitAnomalies(medicines, existing, LoadPriceJump(db, s.PharmacyID))
+
+	names := make([]string, len(medicines))
+	for i, med := range medicines {
+		names[i] = med.Name
+	}
+	// Karantindagi dorilarning eski qiymati replace rejimida ham o'chirilmaydi
+	keep := append([]string{}, names...)
+	for _, item := range flagged {
+		keep = append(keep, item.Medicine.Name)
+		delete(existing, item.Medicine.Name)
+	}
+	diff := computeDiff(existing, medicines)
+
+	// Import tarixi - upsert oldidan dorilar holati saqlanadi
+	importID, err := recordImport(tx, s)
+	if err != nil {
+		return fmt.Errorf("import tarixi yozilmadi: %v", err)
+	}
+	inserted, err := snapshotChanges(tx, importID, s.PharmacyID, names, replace)
+	if err != nil {
+		return fmt.Errorf("import o'zgarishlari saqlanmadi: %v", err)
+	}
+	if err := quarantineMedicines(tx, importID, flagged); err != nil {
+		return fmt.Errorf("karantinga yozilmadi: %v", err)
+	}
+
 	// Batch size - 100 tadan yuklash
 	batchSize := 100
 	saved := 0
@@ -340,24 +492,25 @@ func batchInsertMedicines(db *sql.DB, medicines []Medicine) (int, error) {
 
 		for _, med := range batch {
 			valueStrings = append(valueStrings, fmt.Sprintf(
-				"($%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, NOW())",
-				argPos, argPos+1, argPos+2, argPos+3, argPos+4, argPos+5, argPos+6, argPos+7, argPos+8,
+				"($%d, $%d, $%d, $%d, NULLIF($%d, ''), $%d, $%d, $%d, $%d, $%d, NOW())",
+				argPos, argPos+1, argPos+2, argPos+3, argPos+4, argPos+5, argPos+6, argPos+7, argPos+8, argPos+9,
 			))
 			valueArgs = append(valueArgs, 
-				med.Name, med.Price, med.Count, med.Manufacturer, 
+				med.Name, med.Price, med.Count, med.Manufacturer, med.Barcode,
 				med.Phone, med.Address, med.Description, med.Category, med.PharmacyID,
 			)
-			argPos += 9
+			argPos += 10
 		}
 
 		// Bitta katta INSERT query
 		query := `
-			INSERT INTO medicines (name, price, count, manufacturer, phone, address, description, category, pharmacy_id, updated_at)
+			INSERT INTO medicines (name, price, count, manufacturer, barcode, phone, address, description, category, pharmacy_id, updated_at)
 			VALUES ` + strings.Join(valueStrings, ", ") + `
 			ON CONFLICT (name, pharmacy_id) DO UPDATE SET
 				price = EXCLUDED.price,
 				count = EXCLUDED.count,
 				manufacturer = EXCLUDED.manufacturer,
+				barcode = COALESCE(EXCLUDED.barcode, medicines.barcode),
 				phone = EXCLUDED.phone,
 				address = EXCLUDED.address,
 				description = EXCLUDED.description,
@@ -367,18 +520,60 @@ func batchInsertMedicines(db *sql.DB, medicines []Medicine) (int, error) {
 
 		_, err := tx.Exec(query, valueArgs...)
 		if err != nil {
-			return saved, fmt.Errorf("batch insert xato: %v", err)
+			return fmt.Errorf("batch insert xato: %v", err)
 		}
 
 		saved += len(batch)
 		fmt.Printf("  ✅ %d/%d yuklandi\n", saved, len(medicines))
+		if reporter != nil {
+			reporter.Progress(saved, len(medicines))
+		}
+	}
+
+	// Partiyalar - fayldagi dorilarniki to'liq almashtiriladi
+	if err := replaceBatches(tx, s.PharmacyID, names, medicines); err != nil {
+		return fmt.Errorf("partiyalar saqlanmadi: %v", err)
+	}
+
+	// To'liq sinxronizatsiya - fayldagi nomlar ro'yxatida yo'q dorilarni o'chirish
+	removed := 0
+	if replace {
+		_, err := tx.Exec(
+			"DELETE FROM medicine_batches WHERE pharmacy_id = $1 AND NOT (name = ANY($2))",
+			s.PharmacyID, pq.Array(keep),
+		)
+		if err != nil {
+			return fmt.Errorf("eski partiyalar o'chirilmadi: %v", err)
+		}
+		res, err := tx.Exec(
+			"DELETE FROM medicines WHERE pharmacy_id = $1 AND NOT (name = ANY($2))",
+			s.PharmacyID, pq.Array(keep),
+		)
+		if err != nil {
+			return fmt.Errorf("eski dorilar o'chirilmadi: %v", err)
+		}
+		n, _ := res.RowsAffected()
+		removed = int(n)
+		fmt.Printf("  🗑 %d ta eski dori o'chirildi\n", removed)
+	}
+
+	if err := finishImport(tx, importID, saved, inserted, removed); err != nil {
+		return fmt.Errorf("import tarixi yozilmadi: %v", err)
 	}
 
 	// Transaction ni commit qilish
 	if err := tx.Commit(); err != nil {
-		return saved, fmt.Errorf("commit xato: %v", err)
+		return fmt.Errorf("commit xato: %v", err)
 	}
 
+	res.ImportID = importID
+	res.Saved = saved
+	res.Inserted = inserted
+	res.Updated = saved - inserted
+	res.Removed = removed
+	res.Quarantined = flagged
+	res.Diff = diff
+
 	fmt.Println("✅ Batch insert tugadi!")
-	return saved, nil
+	return nil
 }
\ No newline at end of file
@@ -0,0 +1,170 @@
+package excel
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// ExpiryWindowsSettingKey - settings jadvalida ogohlantirish oynalari (kunlar, "30,60,90")
+const ExpiryWindowsSettingKey = "expiry_windows"
+
+// DefaultExpiryWindows - sozlanmagan bo'lsa
+var DefaultExpiryWindows = []int{30, 60, 90}
+
+// ExpiringBatch - muddati yaqinlashgan yoki o'tgan partiya
+type ExpiringBatch struct {
+	PharmacyID int
+	Name       string
+	Series     string
+	Expiry     time.Time
+	Count      int
+	DaysLeft   int // manfiy - muddati o'tgan
+}
+
+// ParseExpiryWindows - "30,60,90" -> [30 60 90] (o'sish tartibida)
+func ParseExpiryWindows(s string) ([]int, error) {
+	var windows []int
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		days, err := strconv.Atoi(part)
+		if err != nil || days < 1 || days > 365 {
+			return nil, fmt.Errorf("kunlar 1 dan 365 gacha bo'lishi kerak: %s", part)
+		}
+		windows = append(windows, days)
+	}
+	if len(windows) == 0 {
+		return nil, fmt.Errorf("kamida bitta muddat kerak")
+	}
+	sort.Ints(windows)
+	return windows, nil
+}
+
+// LoadExpiryWindows - dorixona sozlamasi (noto'g'ri yoki yo'q bo'lsa standart)
+func LoadExpiryWindows(db *sql.DB, pharmacyID int) []int {
+	var value string
+	db.QueryRow("SELECT value FROM settings WHERE key = $1 AND pharmacy_id = $2", ExpiryWindowsSettingKey, pharmacyID).Scan(&value)
+	windows, err := ParseExpiryWindows(value)
+	if err != nil {
+		return DefaultExpiryWindows
+	}
+	return windows
+}
+
+// ExpiringBatches - muddati within kun ichida tugaydigan va o'tgan partiyalar (qoldig'i bor).
+// pharmacyID 0 bo'lsa barcha dorixonalar.
+func ExpiringBatches(db *sql.DB, pharmacyID, within int) ([]ExpiringBatch, error) {
+	rows, err := db.Query(`
+		SELECT b.pharmacy_id, b.name, b.series, b.expiry_date, b.count, b.expiry_date - CURRENT_DATE
+		FROM medicine_batches b
+		JOIN medicines m ON m.pharmacy_id = b.pharmacy_id AND m.name = b.name
+		WHERE ($1 = 0 OR b.pharmacy_id = $1)
+		  AND b.count > 0
+		  AND b.expiry_date IS NOT NULL
+		  AND b.expiry_date <= CURRENT_DATE + $2::INT
+		ORDER BY b.pharmacy_id, b.expiry_date, b.name
+	`, pharmacyID, within)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var batches []ExpiringBatch
+	for rows.Next() {
+		var b ExpiringBatch
+		if err := rows.Scan(&b.PharmacyID, &b.Name, &b.Series, &b.Expiry, &b.Count, &b.DaysLeft); err != nil {
+			return nil, err
+		}
+		batches = append(batches, b)
+	}
+	return batches, rows.Err()
+}
+
+// ExpiryBucket - partiya qaysi oynaga tushadi: -1 muddati o'tgan, aks holda oyna (kun)
+func ExpiryBucket(daysLeft int, windows []int) int {
+	if daysLeft < 0 {
+		return -1
+	}
+	for _, w := range windows {
+		if daysLeft <= w {
+			return w
+		}
+	}
+	return windows[len(windows)-1]
+}
+
+// ExpiryReport - muddati yaqin partiyalar .xlsx ko'rinishida.
+// windows - har bir dorixonaning oynalari (yo'q bo'lsa standart).
+func ExpiryReport(batches []ExpiringBatch, windows map[int][]int, pharmacyNames map[int]string) ([]byte, error) {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	sheet := "Muddatlar"
+	f.SetSheetName(f.GetSheetName(0), sheet)
+
+	headers := []interface{}{"Dorixona", "Dori nomi", "Seriya", "Yaroqlilik muddati", "Qolgan kun", "Miqdor", "Holat"}
+	if err := f.SetSheetRow(sheet, "A1", &headers); err != nil {
+		return nil, err
+	}
+
+	bold, err := f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}})
+	if err != nil {
+		return nil, err
+	}
+	expiredStyle, err := f.NewStyle(&excelize.Style{
+		Fill: excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"FFC7CE"}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	f.SetCellStyle(sheet, "A1", "G1", bold)
+	f.SetColWidth(sheet, "A", "A", 18)
+	f.SetColWidth(sheet, "B", "B", 45)
+	f.SetColWidth(sheet, "C", "C", 14)
+	f.SetColWidth(sheet, "D", "D", 18)
+	f.SetColWidth(sheet, "E", "G", 12)
+	f.SetPanes(sheet, &excelize.Panes{Freeze: true, YSplit: 1, TopLeftCell: "A2", ActivePane: "bottomLeft"})
+
+	for i, b := range batches {
+		pharmacy := pharmacyNames[b.PharmacyID]
+		if pharmacy == "" {
+			pharmacy = fmt.Sprintf("Dorixona %d", b.PharmacyID)
+		}
+
+		pharmacyWindows, ok := windows[b.PharmacyID]
+		if !ok {
+			pharmacyWindows = DefaultExpiryWindows
+		}
+		status := "O'tgan"
+		if bucket := ExpiryBucket(b.DaysLeft, pharmacyWindows); bucket > 0 {
+			status = fmt.Sprintf("%d kungacha", bucket)
+		}
+
+		row := []interface{}{pharmacy, b.Name, b.Series, b.Expiry.Format("02.01.2006"), b.DaysLeft, b.Count, status}
+		cell := fmt.Sprintf("A%d", i+2)
+		if err := f.SetSheetRow(sheet, cell, &row); err != nil {
+			return nil, err
+		}
+		if b.DaysLeft < 0 {
+			f.SetCellStyle(sheet, cell, fmt.Sprintf("G%d", i+2), expiredStyle)
+		}
+	}
+
+	if len(batches) > 0 {
+		f.AutoFilter(sheet, fmt.Sprintf("A1:G%d", len(batches)+1), nil)
+	}
+
+	buf, err := f.WriteToBuffer()
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
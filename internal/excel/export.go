@@ -0,0 +1,143 @@
+package excel
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+
+	"testuchun/internal/money"
+)
+
+// exportHeaders - eksport faylidagi ustunlar
+var exportHeaders = []interface{}{"Nomi", "Narx", "Miqdor", "Ishlab chiqaruvchi", "Kategoriya", "Shtrix-kod", "Yangilangan"}
+
+// ExportInventory - dorixonalar dorilari .xlsx ko'rinishida, har bir dorixona alohida sheetda.
+// Katta bazalar uchun StreamWriter ishlatiladi - qatorlar xotirada to'planmaydi.
+func ExportInventory(db *sql.DB, pharmacyIDs []int, pharmacyNames map[int]string) ([]byte, error) {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	bold, err := f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}})
+	if err != nil {
+		return nil, err
+	}
+	priceFormat := "#,##0.00"
+	priceStyle, err := f.NewStyle(&excelize.Style{CustomNumFmt: &priceFormat})
+	if err != nil {
+		return nil, err
+	}
+
+	defaultSheet := f.GetSheetName(0)
+	used := make(map[string]bool)
+	for i, pharmacyID := range pharmacyIDs {
+		sheet := exportSheetName(pharmacyNames[pharmacyID], pharmacyID, used)
+		if i == 0 {
+			f.SetSheetName(defaultSheet, sheet)
+		} else if _, err := f.NewSheet(sheet); err != nil {
+			return nil, err
+		}
+
+		if err := writeInventorySheet(db, f, sheet, pharmacyID, bold, priceStyle); err != nil {
+			return nil, fmt.Errorf("dorixona %d eksport qilinmadi: %v", pharmacyID, err)
+		}
+	}
+
+	buf, err := f.WriteToBuffer()
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeInventorySheet(db *sql.DB, f *excelize.File, sheet string, pharmacyID, bold, priceStyle int) error {
+	sw, err := f.NewStreamWriter(sheet)
+	if err != nil {
+		return err
+	}
+
+	sw.SetColWidth(1, 1, 45)
+	sw.SetColWidth(2, 3, 12)
+	sw.SetColWidth(4, 5, 22)
+	sw.SetColWidth(6, 7, 16)
+	sw.SetPanes(&excelize.Panes{Freeze: true, YSplit: 1, TopLeftCell: "A2", ActivePane: "bottomLeft"})
+
+	header := make([]interface{}, len(exportHeaders))
+	for i, h := range exportHeaders {
+		header[i] = excelize.Cell{StyleID: bold, Value: h}
+	}
+	if err := sw.SetRow("A1", header); err != nil {
+		return err
+	}
+
+	rows, err := db.Query(`
+		SELECT name, price, count, COALESCE(manufacturer, ''), COALESCE(category, ''),
+		       COALESCE(barcode, ''), updated_at
+		FROM medicines
+		WHERE pharmacy_id = $1
+		ORDER BY name
+	`, pharmacyID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	rowNum := 1
+	for rows.Next() {
+		var name, manufacturer, category, barcode string
+		var price money.Money
+		var count int
+		var updated sql.NullTime
+		if err := rows.Scan(&name, &price, &count, &manufacturer, &category, &barcode, &updated); err != nil {
+			return err
+		}
+
+		updatedStr := ""
+		if updated.Valid {
+			updatedStr = updated.Time.Format("02.01.2006 15:04")
+		}
+
+		rowNum++
+		cell, _ := excelize.CoordinatesToCellName(1, rowNum)
+		row := []interface{}{
+			name,
+			excelize.Cell{StyleID: priceStyle, Value: float64(price) / 100},
+			count, manufacturer, category, barcode, updatedStr,
+		}
+		if err := sw.SetRow(cell, row); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	return sw.Flush()
+}
+
+// exportSheetName - Excel sheet nomi: 31 belgigacha, taqiqlangan belgilarsiz, takrorlanmas
+func exportSheetName(name string, pharmacyID int, used map[string]bool) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return ' '
+		}
+		return r
+	}, strings.TrimSpace(name))
+	if name == "" {
+		name = fmt.Sprintf("Dorixona %d", pharmacyID)
+	}
+	if r := []rune(name); len(r) > 31 {
+		name = string(r[:31])
+	}
+	if used[strings.ToLower(name)] {
+		name = fmt.Sprintf("Dorixona %d", pharmacyID)
+	}
+	used[strings.ToLower(name)] = true
+	return name
+}
+
+// ExportFileName - "dorilar_1_2026-10-16.xlsx" yoki "dorilar_all_..."
+func ExportFileName(scope string) string {
+	return fmt.Sprintf("dorilar_%s_%s.xlsx", scope, time.Now().Format("2006-01-02"))
+}
@@ -0,0 +1,245 @@
+package excel
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// ImportRecord - imports jadvalidagi bitta yozuv
+type ImportRecord struct {
+	ID           int
+	PharmacyID   int
+	UploadedBy   int64
+	FileName     string
+	Checksum     string
+	Mode         string
+	Saved        int
+	Inserted     int
+	Removed      int
+	Skipped      int
+	Duplicates   int
+	CreatedAt    time.Time
+	RolledBackAt *time.Time
+}
+
+// checksumReader - o'qilayotgan baytlardan SHA-256 hisoblash (faylni ikkinchi marta o'qimasdan)
+func checksumReader(r io.Reader) (io.Reader, func() string) {
+	h := sha256.New()
+	return io.TeeReader(r, h), func() string {
+		return hex.EncodeToString(h.Sum(nil))
+	}
+}
+
+// recordImport - import boshlanishini yozish, transaction ichida
+func recordImport(tx *sql.Tx, s *StagedImport) (int, error) {
+	var id int
+	err := tx.QueryRow(`
+		INSERT INTO imports (pharmacy_id, uploaded_by, file_name, checksum, mode, skipped, duplicates)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id
+	`, s.PharmacyID, s.UploadedBy, s.FileName, s.Checksum, s.Mode, s.Skipped, s.Duplicates).Scan(&id)
+	return id, err
+}
+
+// snapshotChanges - upsert oldidan ta'sirlanadigan dorilarning holatini saqlash.
+// Replace rejimida dorixonaning barcha dorilari ta'sirlanadi.
+func snapshotChanges(tx *sql.Tx, importID int, pharmacyID int, names []string, replace bool) (int, error) {
+	_, err := tx.Exec(`
+		INSERT INTO import_changes (import_id, name, existed, price, count, manufacturer, barcode, phone, address, description, category, updated_at)
+		SELECT $1, name, true, price, count, manufacturer, barcode, phone, address, description, category, updated_at
+		FROM medicines
+		WHERE pharmacy_id = $2 AND ($4 OR name = ANY($3))
+	`, importID, pharmacyID, pq.Array(names), replace)
+	if err != nil {
+		return 0, err
+	}
+
+	// Partiyalar ham saqlanadi - rollback'da to'liq qaytariladi
+	_, err = tx.Exec(`
+		INSERT INTO import_batches (import_id, name, series, expiry_date, count)
+		SELECT $1, name, series, expiry_date, count
+		FROM medicine_batches
+		WHERE pharmacy_id = $2 AND ($4 OR name = ANY($3))
+	`, importID, pharmacyID, pq.Array(names), replace)
+	if err != nil {
+		return 0, err
+	}
+
+	// Bazada yo'q nomlar - import bilan yangi qo'shiladi
+	res, err := tx.Exec(`
+		INSERT INTO import_changes (import_id, name, existed)
+		SELECT $1, n, false
+		FROM unnest($3::text[]) AS n
+		WHERE NOT EXISTS (SELECT 1 FROM medicines WHERE pharmacy_id = $2 AND name = n)
+	`, importID, pharmacyID, pq.Array(names))
+	if err != nil {
+		return 0, err
+	}
+	inserted, _ := res.RowsAffected()
+	return int(inserted), nil
+}
+
+// finishImport - yakuniy sonlarni yozish
+func finishImport(tx *sql.Tx, importID, saved, inserted, removed int) error {
+	_, err := tx.Exec(
+		"UPDATE imports SET saved = $2, inserted = $3, removed = $4 WHERE id = $1",
+		importID, saved, inserted, removed,
+	)
+	return err
+}
+
+const importColumns = `id, pharmacy_id, uploaded_by, file_name, COALESCE(checksum, ''), mode,
+	saved, inserted, removed, skipped, duplicates, created_at, rolled_back_at`
+
+func scanImport(scanner interface{ Scan(...interface{}) error }) (*ImportRecord, error) {
+	var rec ImportRecord
+	var rolledBack sql.NullTime
+	err := scanner.Scan(&rec.ID, &rec.PharmacyID, &rec.UploadedBy, &rec.FileName, &rec.Checksum, &rec.Mode,
+		&rec.Saved, &rec.Inserted, &rec.Removed, &rec.Skipped, &rec.Duplicates, &rec.CreatedAt, &rolledBack)
+	if err != nil {
+		return nil, err
+	}
+	if rolledBack.Valid {
+		rec.RolledBackAt = &rolledBack.Time
+	}
+	return &rec, nil
+}
+
+// ListImports - dorixonaning oxirgi importlari
+func ListImports(db *sql.DB, pharmacyID, limit int) ([]ImportRecord, error) {
+	rows, err := db.Query(`SELECT `+importColumns+` FROM imports WHERE pharmacy_id = $1 ORDER BY id DESC LIMIT $2`, pharmacyID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []ImportRecord
+	for rows.Next() {
+		rec, err := scanImport(rows)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, *rec)
+	}
+	return records, rows.Err()
+}
+
+// GetImport - bitta import yozuvi
+func GetImport(db *sql.DB, importID int) (*ImportRecord, error) {
+	rec, err := scanImport(db.QueryRow(`SELECT `+importColumns+` FROM imports WHERE id = $1`, importID))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("import #%d topilmadi", importID)
+	}
+	return rec, err
+}
+
+// RollbackImport - dorixonani import oldidagi holatga qaytarish.
+// Undan keyingi importlar ham teskari tartibda bekor qilinadi. Qaytariladi: bekor qilingan importlar soni.
+func RollbackImport(db *sql.DB, importID int) (int, error) {
+	rec, err := GetImport(db, importID)
+	if err != nil {
+		return 0, err
+	}
+	if rec.RolledBackAt != nil {
+		return 0, fmt.Errorf("import #%d allaqachon bekor qilingan", importID)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("transaction boshlanmadi: %v", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`
+		SELECT id FROM imports
+		WHERE pharmacy_id = $1 AND id >= $2 AND rolled_back_at IS NULL
+		ORDER BY id DESC
+	`, rec.PharmacyID, importID)
+	if err != nil {
+		return 0, err
+	}
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+
+	for _, id := range ids {
+		if err := revertImport(tx, id, rec.PharmacyID); err != nil {
+			return 0, fmt.Errorf("import #%d bekor qilinmadi: %v", id, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("commit xato: %v", err)
+	}
+	return len(ids), nil
+}
+
+// revertImport - bitta import o'zgarishlarini qaytarish
+func revertImport(tx *sql.Tx, importID, pharmacyID int) error {
+	// Import bilan qo'shilgan dorilar o'chiriladi
+	_, err := tx.Exec(`
+		DELETE FROM medicines m
+		USING import_changes c
+		WHERE c.import_id = $1 AND NOT c.existed
+		  AND m.pharmacy_id = $2 AND m.name = c.name
+	`, importID, pharmacyID)
+	if err != nil {
+		return err
+	}
+
+	// Oldin bor bo'lgan dorilar eski holatiga qaytariladi (o'chirilganlar qayta qo'shiladi)
+	_, err = tx.Exec(`
+		INSERT INTO medicines (name, price, count, manufacturer, barcode, phone, address, description, category, pharmacy_id, updated_at)
+		SELECT name, price, count, manufacturer, barcode, phone, address, description, category, $2, updated_at
+		FROM import_changes
+		WHERE import_id = $1 AND existed
+		ON CONFLICT (name, pharmacy_id) DO UPDATE SET
+			price = EXCLUDED.price,
+			count = EXCLUDED.count,
+			manufacturer = EXCLUDED.manufacturer,
+			barcode = EXCLUDED.barcode,
+			phone = EXCLUDED.phone,
+			address = EXCLUDED.address,
+			description = EXCLUDED.description,
+			category = EXCLUDED.category,
+			updated_at = EXCLUDED.updated_at
+	`, importID, pharmacyID)
+	if err != nil {
+		return err
+	}
+
+	// Import ta'sir qilgan dorilarning partiyalari eski holatiga qaytariladi
+	_, err = tx.Exec(`
+		DELETE FROM medicine_batches b
+		USING import_changes c
+		WHERE c.import_id = $1 AND b.pharmacy_id = $2 AND b.name = c.name
+	`, importID, pharmacyID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`
+		INSERT INTO medicine_batches (pharmacy_id, name, series, expiry_date, count)
+		SELECT $2, name, series, expiry_date, count
+		FROM import_batches
+		WHERE import_id = $1
+	`, importID, pharmacyID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("UPDATE imports SET rolled_back_at = NOW() WHERE id = $1", importID)
+	return err
+}
@@ -0,0 +1,238 @@
+package excel
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// ProfileSettingKey - settings jadvalida import profili saqlanadigan key
+const ProfileSettingKey = "import_profile"
+
+// ImportProfile - dorixona yetkazib beruvchisi fayli tuzilishi
+type ImportProfile struct {
+	Sheet            string         `json:"sheet,omitempty"`      // sheet nomi (bo'sh = birinchi sheet)
+	HeaderRow        int            `json:"header_row,omitempty"` // sarlavha qatori, 1 dan (0 = avtomatik)
+	Columns          map[string]int `json:"columns,omitempty"`    // maydon -> ustun indeksi, 0 dan
+	DecimalSeparator string         `json:"decimal,omitempty"`    // "," yoki "." (bo'sh = avtomatik)
+	SkipRows         []int          `json:"skip_rows,omitempty"`  // o'tkazib yuboriladigan qatorlar, 1 dan
+}
+
+// LoadProfile - dorixonaning faol import profilini o'qish (yo'q bo'lsa nil)
+func LoadProfile(db *sql.DB, pharmacyID int) (*ImportProfile, error) {
+	var value string
+	err := db.QueryRow("SELECT value FROM settings WHERE key = $1 AND pharmacy_id = $2", ProfileSettingKey, pharmacyID).Scan(&value)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("import profili o'qilmadi: %v", err)
+	}
+	if value == "" {
+		return nil, nil
+	}
+
+	var profile ImportProfile
+	if err := json.Unmarshal([]byte(value), &profile); err != nil {
+		return nil, fmt.Errorf("import profili buzilgan: %v", err)
+	}
+	return &profile, nil
+}
+
+// JSON - settings ga yozish uchun
+func (p *ImportProfile) JSON() string {
+	data, _ := json.Marshal(p)
+	return string(data)
+}
+
+// ParseProfile - "sheet=Лист1 header=3 name=B price=E decimal=, skip=1,2" ko'rinishidagi matnni o'qish
+func ParseProfile(input string) (*ImportProfile, error) {
+	profile := &ImportProfile{}
+
+	for _, part := range strings.Fields(input) {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 || kv[1] == "" {
+			return nil, fmt.Errorf("noto'g'ri qism: %s", part)
+		}
+		key, value := strings.ToLower(kv[0]), kv[1]
+
+		switch key {
+		case "sheet":
+			// sheet nomida bo'sh joy bo'lsa "_" bilan yoziladi
+			profile.Sheet = strings.ReplaceAll(value, "_", " ")
+		case "header":
+			row, err := strconv.Atoi(value)
+			if err != nil || row < 1 {
+				return nil, fmt.Errorf("header qator raqami bo'lishi kerak: %s", value)
+			}
+			profile.HeaderRow = row
+		case "decimal":
+			if value != "," && value != "." {
+				return nil, fmt.Errorf("decimal faqat , yoki . bo'lishi mumkin")
+			}
+			profile.DecimalSeparator = value
+		case "skip":
+			rows, err := parseRowList(value)
+			if err != nil {
+				return nil, err
+			}
+			profile.SkipRows = rows
+		case fieldNum, fieldName, fieldCount, fieldPrice, fieldManufacturer, fieldBarcode, fieldSeries, fieldExpiry, fieldCategory:
+			idx, err := parseColumn(value)
+			if err != nil {
+				return nil, err
+			}
+			if profile.Columns == nil {
+				profile.Columns = make(map[string]int)
+			}
+			profile.Columns[key] = idx
+		default:
+			return nil, fmt.Errorf("noma'lum sozlama: %s", key)
+		}
+	}
+
+	if len(profile.Columns) > 0 && !columnMap(profile.Columns).recognized() {
+		return nil, fmt.Errorf("kamida name va price ustunlari kerak")
+	}
+	return profile, nil
+}
+
+// parseColumn - "B" yoki "2" -> 0 dan boshlangan indeks
+func parseColumn(value string) (int, error) {
+	if n, err := strconv.Atoi(value); err == nil {
+		if n < 1 {
+			return 0, fmt.Errorf("ustun raqami 1 dan boshlanadi: %s", value)
+		}
+		return n - 1, nil
+	}
+
+	n, err := excelize.ColumnNameToNumber(value)
+	if err != nil {
+		return 0, fmt.Errorf("noto'g'ri ustun: %s", value)
+	}
+	return n - 1, nil
+}
+
+// maxSkipRows - skip= ro'yxatidagi qatorlar chegarasi (katta oraliq xotirani to'ldirmasin)
+const maxSkipRows = 1000
+
+// parseRowList - "1,2,5-7" -> [1 2 5 6 7]
+func parseRowList(value string) ([]int, error) {
+	var rows []int
+	for _, part := range strings.Split(value, ",") {
+		bounds := strings.SplitN(part, "-", 2)
+		from, err := strconv.Atoi(bounds[0])
+		if err != nil || from < 1 {
+			return nil, fmt.Errorf("noto'g'ri qator: %s", part)
+		}
+		to := from
+		if len(bounds) == 2 {
+			to, err = strconv.Atoi(bounds[1])
+			if err != nil || to < from {
+				return nil, fmt.Errorf("noto'g'ri oraliq: %s", part)
+			}
+		}
+		if to-from+1 > maxSkipRows-len(rows) {
+			return nil, fmt.Errorf("juda ko'p qator: %d tadan oshmasligi kerak", maxSkipRows)
+		}
+		for r := from; r <= to; r++ {
+			rows = append(rows, r)
+		}
+	}
+	return rows, nil
+}
+
+// String - profilni admin uchun ko'rsatish
+func (p *ImportProfile) String() string {
+	var b strings.Builder
+
+	sheet := p.Sheet
+	if sheet == "" {
+		sheet = "birinchi sheet"
+	}
+	b.WriteString(fmt.Sprintf("📄 Sheet: %s\n", sheet))
+
+	if p.HeaderRow > 0 {
+		b.WriteString(fmt.Sprintf("📋 Sarlavha qatori: %d\n", p.HeaderRow))
+	} else {
+		b.WriteString("📋 Sarlavha qatori: avtomatik\n")
+	}
+
+	if len(p.Columns) > 0 {
+		b.WriteString("🔢 Ustunlar:")
+		for _, field := range headerFieldOrder {
+			if idx, ok := p.Columns[field]; ok {
+				name, _ := excelize.ColumnNumberToName(idx + 1)
+				b.WriteString(fmt.Sprintf(" %s=%s", field, name))
+			}
+		}
+		b.WriteString("\n")
+	} else {
+		b.WriteString("🔢 Ustunlar: sarlavha bo'yicha\n")
+	}
+
+	if p.DecimalSeparator != "" {
+		b.WriteString(fmt.Sprintf("💰 Kasr ajratuvchi: %s\n", p.DecimalSeparator))
+	}
+
+	if len(p.SkipRows) > 0 {
+		rows := append([]int(nil), p.SkipRows...)
+		sort.Ints(rows)
+		parts := make([]string, len(rows))
+		for i, r := range rows {
+			parts[i] = strconv.Itoa(r)
+		}
+		b.WriteString(fmt.Sprintf("⏭ O'tkaziladigan qatorlar: %s\n", strings.Join(parts, ",")))
+	}
+
+	return b.String()
+}
+
+// resolveColumns - profil bo'yicha ustunlarni aniqlash, bo'lmasa sarlavhani qidirish
+func resolveColumns(rows [][]string, profile *ImportProfile) (columnMap, int, bool) {
+	if profile != nil && len(profile.Columns) > 0 {
+		return columnMap(profile.Columns), profile.HeaderRow - 1, true
+	}
+
+	if profile != nil && profile.HeaderRow > 0 && profile.HeaderRow <= len(rows) {
+		cols := matchHeader(rows[profile.HeaderRow-1])
+		if cols.recognized() {
+			return cols, profile.HeaderRow - 1, true
+		}
+	}
+
+	return findHeader(rows)
+}
+
+// skipSet - o'tkaziladigan qatorlar (0 dan boshlangan indekslar)
+func (p *ImportProfile) skipSet() map[int]bool {
+	skip := make(map[int]bool)
+	if p == nil {
+		return skip
+	}
+	for _, r := range p.SkipRows {
+		skip[r-1] = true
+	}
+	return skip
+}
+
+// decimal - profil yo'q bo'lsa avtomatik
+func (p *ImportProfile) decimal() string {
+	if p == nil {
+		return ""
+	}
+	return p.DecimalSeparator
+}
+
+// sheet - profil yo'q bo'lsa birinchi sheet
+func (p *ImportProfile) sheet() string {
+	if p == nil {
+		return ""
+	}
+	return p.Sheet
+}
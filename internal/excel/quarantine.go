@@ -0,0 +1,370 @@
+package excel
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"testuchun/internal/money"
+)
+
+// PriceJumpSettingKey - settings jadvalida narx sakrashi chegarasi (foizda)
+const PriceJumpSettingKey = "price_jump_percent"
+
+// DefaultPriceJumpPercent - narx 4 barobardan ko'p oshsa yoki tushsa karantinga tushadi
+const DefaultPriceJumpPercent = 300
+
+// MaxReasonableCount - bundan katta miqdor xato deb hisoblanadi
+const MaxReasonableCount = 100000
+
+// QuarantineItem - tekshiruvdan o'tmagan, admin qarorini kutayotgan dori
+type QuarantineItem struct {
+	ID         int
+	PharmacyID int
+	ImportID   int
+	Medicine   Medicine
+	Existed    bool // bazada shu nomli dori bor edi
+	OldPrice   money.Money
+	OldCount   int
+	Reason     string
+	CreatedAt  time.Time
+}
+
+// ParsePriceJump - "300" -> 300 (10% dan 10000% gacha)
+func ParsePriceJump(s string) (int, error) {
+	percent, err := strconv.Atoi(strings.TrimSuffix(strings.TrimSpace(s), "%"))
+	if err != nil || percent < 10 || percent > 10000 {
+		return 0, fmt.Errorf("foiz 10 dan 10000 gacha bo'lishi kerak")
+	}
+	return percent, nil
+}
+
+// LoadPriceJump - dorixona sozlamasi (noto'g'ri yoki yo'q bo'lsa standart)
+func LoadPriceJump(db *sql.DB, pharmacyID int) int {
+	var value string
+	db.QueryRow("SELECT value FROM settings WHERE key = $1 AND pharmacy_id = $2", PriceJumpSettingKey, pharmacyID).Scan(&value)
+	percent, err := ParsePriceJump(value)
+	if err != nil {
+		return DefaultPriceJumpPercent
+	}
+	return percent
+}
+
+// checkAnomaly - dorini qoidalar va bazadagi qiymat bilan tekshirish; muammo bo'lmasa bo'sh satr
+func checkAnomaly(med Medicine, old Medicine, existed bool, jumpPercent int) string {
+	var reasons []string
+	if strings.TrimSpace(med.Name) == "" {
+		reasons = append(reasons, "nomi bo'sh")
+	}
+	if med.Price <= 0 {
+		reasons = append(reasons, "narx 0 yoki manfiy")
+	}
+	if med.Count < 0 {
+		reasons = append(reasons, "miqdor manfiy")
+	}
+	if med.Count > MaxReasonableCount {
+		reasons = append(reasons, fmt.Sprintf("miqdor juda katta (%d dan ortiq)", MaxReasonableCount))
+	}
+	// Sakrash ikki tomonga ham tekshiriladi: 300% - 4 barobar oshish yoki 4 barobar tushish
+	if existed && old.Price > 0 && med.Price > 0 {
+		factor := 1 + float64(jumpPercent)/100
+		ratio := float64(med.Price) / float64(old.Price)
+		if ratio > factor || ratio < 1/factor {
+			pct, _ := MedicineChange{OldPrice: old.Price, NewPrice: med.Price}.PricePercent()
+			reasons = append(reasons, fmt.Sprintf("narx keskin o'zgardi: %s → %s (%s)",
+				old.Price.Format(), med.Price.Format(), formatPercent(pct)))
+		}
+	}
+	return strings.Join(reasons, "; ")
+}
+
+// splitAnomalies - yoziladigan dorilar va karantinga tushadiganlar
+func splitAnomalies(medicines []Medicine, existing map[string]Medicine, jumpPercent int) ([]Medicine, []QuarantineItem) {
+	var accepted []Medicine
+	var flagged []QuarantineItem
+	for _, med := range medicines {
+		old, existed := existing[med.Name]
+		reason := checkAnomaly(med, old, existed, jumpPercent)
+		if reason == "" {
+			accepted = append(accepted, med)
+			continue
+		}
+		flagged = append(flagged, QuarantineItem{
+			PharmacyID: med.PharmacyID,
+			Medicine:   med,
+			Existed:    existed,
+			OldPrice:   old.Price,
+			OldCount:   old.Count,
+			Reason:     reason,
+		})
+	}
+	return accepted, flagged
+}
+
+// quarantineMedicines - shubhali dorilarni karantin jadvaliga yozish, transaction ichida.
+// Shu dorining oldingi kutayotgan yozuvi yangisi bilan almashtiriladi.
+func quarantineMedicines(tx *sql.Tx, importID int, items []QuarantineItem) error {
+	for i := range items {
+		item := &items[i]
+		med := item.Medicine
+
+		batches, err := json.Marshal(med.Batches)
+		if err != nil {
+			return err
+		}
+
+		var oldPrice interface{}
+		var oldCount interface{}
+		if item.Existed {
+			oldPrice, oldCount = item.OldPrice, item.OldCount
+		}
+
+		if _, err := tx.Exec(
+			"DELETE FROM import_quarantine WHERE pharmacy_id = $1 AND name = $2",
+			med.PharmacyID, med.Name,
+		); err != nil {
+			return err
+		}
+		err = tx.QueryRow(`
+			INSERT INTO import_quarantine (pharmacy_id, import_id, name, price, count, manufacturer, barcode,
+				phone, address, description, category, batches, old_price, old_count, reason)
+			VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), $8, $9, $10, $11, $12, $13, $14, $15)
+			RETURNING id, created_at
+		`, med.PharmacyID, importID, med.Name, med.Price, med.Count, med.Manufacturer, med.Barcode,
+			med.Phone, med.Address, med.Description, med.Category, string(batches), oldPrice, oldCount, item.Reason,
+		).Scan(&item.ID, &item.CreatedAt)
+		if err != nil {
+			return err
+		}
+		item.ImportID = importID
+	}
+	return nil
+}
+
+const quarantineColumns = `id, pharmacy_id, COALESCE(import_id, 0), name, price, count, COALESCE(manufacturer, ''),
+	COALESCE(barcode, ''), COALESCE(phone, ''), COALESCE(address, ''), COALESCE(description, ''),
+	COALESCE(category, ''), COALESCE(batches, '[]'), old_price IS NOT NULL, COALESCE(old_price, 0),
+	COALESCE(old_count, 0), reason, created_at`
+
+func scanQuarantine(scanner interface{ Scan(...interface{}) error }) (*QuarantineItem, error) {
+	var item QuarantineItem
+	var batches []byte
+	med := &item.Medicine
+	err := scanner.Scan(&item.ID, &item.PharmacyID, &item.ImportID, &med.Name, &med.Price, &med.Count,
+		&med.Manufacturer, &med.Barcode, &med.Phone, &med.Address, &med.Description, &med.Category,
+		&batches, &item.Existed, &item.OldPrice, &item.OldCount, &item.Reason, &item.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(batches, &med.Batches); err != nil {
+		return nil, fmt.Errorf("partiyalar o'qilmadi: %v", err)
+	}
+	med.PharmacyID = item.PharmacyID
+	return &item, nil
+}
+
+// ListQuarantine - dorixonaning kutayotgan dorilari (eng eskisi birinchi) va umumiy soni
+func ListQuarantine(db *sql.DB, pharmacyID, limit int) ([]QuarantineItem, int, error) {
+	var total int
+	if err := db.QueryRow("SELECT COUNT(*) FROM import_quarantine WHERE pharmacy_id = $1", pharmacyID).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := db.Query(`SELECT `+quarantineColumns+` FROM import_quarantine WHERE pharmacy_id = $1 ORDER BY id LIMIT $2`, pharmacyID, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var items []QuarantineItem
+	for rows.Next() {
+		item, err := scanQuarantine(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		items = append(items, *item)
+	}
+	return items, total, rows.Err()
+}
+
+// GetQuarantine - bitta karantin yozuvi
+func GetQuarantine(db *sql.DB, id int) (*QuarantineItem, error) {
+	item, err := scanQuarantine(db.QueryRow(`SELECT `+quarantineColumns+` FROM import_quarantine WHERE id = $1`, id))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("karantin yozuvi #%d topilmadi", id)
+	}
+	return item, err
+}
+
+// ErrQuarantineStale - dori karantinga tushgandan keyin bazada o'zgargan (yangi import yoki qo'lda)
+var ErrQuarantineStale = errors.New("dori karantinga tushgandan keyin o'zgargan - faylni qayta yuklang yoki rad eting")
+
+// ApproveQuarantine - dorini bazaga yozish va karantindan chiqarish. O'zgarish asl import
+// tarixiga yoziladi (/rollback uni ham qaytaradi). Dori karantindan keyin o'zgargan bo'lsa
+// ErrQuarantineStale qaytadi - eski qiymat yangisining ustiga yozilmaydi.
+func ApproveQuarantine(db *sql.DB, id int) (*QuarantineItem, error) {
+	item, err := GetQuarantine(db, id)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("transaction boshlanmadi: %v", err)
+	}
+	defer tx.Rollback()
+
+	med := item.Medicine
+	if err := checkQuarantineCurrent(tx, item); err != nil {
+		return nil, err
+	}
+	if err := recordQuarantineChange(tx, item); err != nil {
+		return nil, fmt.Errorf("import tarixi yozilmadi: %v", err)
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO medicines (name, price, count, manufacturer, barcode, phone, address, description, category, pharmacy_id, updated_at)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6, $7, $8, $9, $10, NOW())
+		ON CONFLICT (name, pharmacy_id) DO UPDATE SET
+			price = EXCLUDED.price,
+			count = EXCLUDED.count,
+			manufacturer = EXCLUDED.manufacturer,
+			barcode = COALESCE(EXCLUDED.barcode, medicines.barcode),
+			phone = EXCLUDED.phone,
+			address = EXCLUDED.address,
+			description = EXCLUDED.description,
+			category = EXCLUDED.category,
+			updated_at = NOW()
+	`, med.Name, med.Price, med.Count, med.Manufacturer, med.Barcode,
+		med.Phone, med.Address, med.Description, med.Category, item.PharmacyID)
+	if err != nil {
+		return nil, fmt.Errorf("dori saqlanmadi: %v", err)
+	}
+
+	if err := replaceBatches(tx, item.PharmacyID, []string{med.Name}, []Medicine{med}); err != nil {
+		return nil, fmt.Errorf("partiyalar saqlanmadi: %v", err)
+	}
+
+	if _, err := tx.Exec("DELETE FROM import_quarantine WHERE id = $1", id); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit xato: %v", err)
+	}
+	return item, nil
+}
+
+// checkQuarantineCurrent - bazadagi joriy qiymat karantin paytidagi bilan bir xilmi (qator qulflanadi)
+func checkQuarantineCurrent(tx *sql.Tx, item *QuarantineItem) error {
+	var price money.Money
+	var count int
+	err := tx.QueryRow(
+		"SELECT price, count FROM medicines WHERE pharmacy_id = $1 AND name = $2 FOR UPDATE",
+		item.PharmacyID, item.Medicine.Name,
+	).Scan(&price, &count)
+	switch {
+	case err == sql.ErrNoRows:
+		if item.Existed {
+			return ErrQuarantineStale
+		}
+		return nil
+	case err != nil:
+		return err
+	case !item.Existed || price != item.OldPrice || count != item.OldCount:
+		return ErrQuarantineStale
+	}
+	return nil
+}
+
+// recordQuarantineChange - tasdiqlangan dorining oldingi holati asl importning
+// import_changes yozuvlariga qo'shiladi (replace rejimida allaqachon saqlangan bo'lishi mumkin)
+func recordQuarantineChange(tx *sql.Tx, item *QuarantineItem) error {
+	if item.ImportID == 0 {
+		return fmt.Errorf("karantin yozuvining importi topilmadi")
+	}
+	var rolledBack bool
+	if err := tx.QueryRow("SELECT rolled_back_at IS NOT NULL FROM imports WHERE id = $1", item.ImportID).Scan(&rolledBack); err != nil {
+		return err
+	}
+	if rolledBack {
+		return fmt.Errorf("import #%d bekor qilingan", item.ImportID)
+	}
+
+	var recorded bool
+	err := tx.QueryRow(
+		"SELECT EXISTS (SELECT 1 FROM import_changes WHERE import_id = $1 AND name = $2)",
+		item.ImportID, item.Medicine.Name,
+	).Scan(&recorded)
+	if err != nil {
+		return err
+	}
+	inserted := 0
+	if !recorded {
+		if inserted, err = snapshotChanges(tx, item.ImportID, item.PharmacyID, []string{item.Medicine.Name}, false); err != nil {
+			return err
+		}
+	}
+	_, err = tx.Exec(
+		"UPDATE imports SET saved = saved + 1, inserted = inserted + $2 WHERE id = $1",
+		item.ImportID, inserted,
+	)
+	return err
+}
+
+// RejectQuarantine - dorini bazaga yozmasdan karantindan o'chirish
+func RejectQuarantine(db *sql.DB, id int) (*QuarantineItem, error) {
+	item, err := GetQuarantine(db, id)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.Exec("DELETE FROM import_quarantine WHERE id = $1", id); err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// ResolveAllQuarantine - dorixonaning barcha kutayotgan dorilarini tasdiqlash yoki rad etish.
+// Qaytariladi: qayta ishlangan yozuvlar soni va o'zgargani uchun karantinda qoldirilganlar soni.
+func ResolveAllQuarantine(db *sql.DB, pharmacyID int, approve bool) (int, int, error) {
+	if !approve {
+		res, err := db.Exec("DELETE FROM import_quarantine WHERE pharmacy_id = $1", pharmacyID)
+		if err != nil {
+			return 0, 0, err
+		}
+		n, _ := res.RowsAffected()
+		return int(n), 0, nil
+	}
+
+	rows, err := db.Query("SELECT id FROM import_quarantine WHERE pharmacy_id = $1 ORDER BY id", pharmacyID)
+	if err != nil {
+		return 0, 0, err
+	}
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, 0, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+
+	approved, stale := 0, 0
+	for _, id := range ids {
+		_, err := ApproveQuarantine(db, id)
+		if err == ErrQuarantineStale {
+			stale++
+			continue
+		}
+		if err != nil {
+			return approved, stale, err
+		}
+		approved++
+	}
+	return approved, stale, nil
+}
@@ -0,0 +1,49 @@
+package excel
+
+import (
+	"strings"
+	"testing"
+
+	"testuchun/internal/money"
+)
+
+func TestCheckAnomaly(t *testing.T) {
+	old := Medicine{Name: "Парацетамол", Price: money.FromSom(1000), Count: 10}
+
+	tests := []struct {
+		name    string
+		med     Medicine
+		existed bool
+		jump    int
+		want    string // kutilgan sabab qismi, bo'sh - anomaliya yo'q
+	}{
+		{"oddiy", Medicine{Name: "Парацетамол", Price: money.FromSom(1200), Count: 5}, true, 300, ""},
+		{"narx 0", Medicine{Name: "Парацетамол", Price: 0, Count: 5}, true, 300, "narx 0 yoki manfiy"},
+		{"narx manfiy", Medicine{Name: "Парацетамол", Price: money.FromSom(-5), Count: 5}, true, 300, "narx 0 yoki manfiy"},
+		{"miqdor manfiy", Medicine{Name: "Парацетамол", Price: money.FromSom(1000), Count: -1}, true, 300, "miqdor manfiy"},
+		{"miqdor chegarada", Medicine{Name: "Парацетамол", Price: money.FromSom(1000), Count: MaxReasonableCount}, true, 300, ""},
+		{"miqdor juda katta", Medicine{Name: "Парацетамол", Price: money.FromSom(1000), Count: MaxReasonableCount + 1}, true, 300, "miqdor juda katta"},
+		{"narx 4 barobar", Medicine{Name: "Парацетамол", Price: money.FromSom(4000), Count: 5}, true, 300, ""},
+		{"narx oshishi chegaradan ko'p", Medicine{Name: "Парацетамол", Price: money.FromSom(4001), Count: 5}, true, 300, "narx keskin o'zgardi"},
+		{"narx tushishi chegaradan ko'p", Medicine{Name: "Парацетамол", Price: money.FromSom(249), Count: 5}, true, 300, "narx keskin o'zgardi"},
+		{"sozlangan chegara 50%", Medicine{Name: "Парацетамол", Price: money.FromSom(1600), Count: 5}, true, 50, "narx keskin o'zgardi"},
+		{"birinchi import", Medicine{Name: "Парацетамол", Price: money.FromSom(999999), Count: 5}, false, 300, ""},
+		{"nomi bo'sh", Medicine{Name: " ", Price: money.FromSom(1000), Count: 5}, false, 300, "nomi bo'sh"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var existing Medicine
+			if tt.existed {
+				existing = old
+			}
+			got := checkAnomaly(tt.med, existing, tt.existed, tt.jump)
+			if tt.want == "" && got != "" {
+				t.Fatalf("anomaliya kutilmagan, olindi: %q", got)
+			}
+			if !strings.Contains(got, tt.want) {
+				t.Fatalf("sabab %q bo'lishi kerak, olindi: %q", tt.want, got)
+			}
+		})
+	}
+}
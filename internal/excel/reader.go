@@ -0,0 +1,191 @@
+package excel
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"path"
+	"reflect"
+	"strings"
+
+	"github.com/extrame/xls"
+	"github.com/xuri/excelize/v2"
+)
+
+// Fayl formatlari
+const (
+	formatUnknown = iota
+	formatXLSX
+	formatXLS
+)
+
+var (
+	zipMagic  = []byte{0x50, 0x4B, 0x03, 0x04}                         // .xlsx (ZIP arxiv)
+	ole2Magic = []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1} // .xls (BIFF8, OLE2)
+)
+
+// detectFormat - fayl formatini nomidan emas, tarkibidan aniqlash
+func detectFormat(data []byte) int {
+	switch {
+	case bytes.HasPrefix(data, zipMagic):
+		return formatXLSX
+	case bytes.HasPrefix(data, ole2Magic):
+		return formatXLS
+	}
+	return formatUnknown
+}
+
+// IsImportFile - import qilinadigan fayl turlari: .xlsx, .xls, CSV/TSV/TXT, CommerceML
+func IsImportFile(name string) bool {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".xlsx", ".xls":
+		return true
+	}
+	return IsCSVFile(name) || IsCommerceMLFile(name)
+}
+
+// rowIterator - qatorlarni bittalab o'qish, butun sheet xotiraga yuklanmaydi
+type rowIterator interface {
+	Next() bool
+	Columns() ([]string, error)
+	Err() error
+	Close() error
+}
+
+// openRows - sheet qatorlari iteratori (.xlsx yoki .xls), sheet bo'sh bo'lsa birinchisi.
+// Vaqtinchalik fayl yaratilmaydi - bir vaqtdagi yuklashlar bir-biriga xalaqit bermaydi.
+// Cheklov: ZIP (.xlsx) va OLE2 (.xls) ixtiyoriy joydan o'qishni talab qiladi, shuning uchun
+// siqilgan fayl bir marta xotiraga o'qiladi; qatorlarning o'zi oqim bilan yuradi.
+func openRows(r io.Reader, sheet string) (rowIterator, error) {
+	br := bufio.NewReader(r)
+	head, _ := br.Peek(len(ole2Magic))
+
+	switch detectFormat(head) {
+	case formatXLSX:
+		return openXLSXRows(br, sheet)
+	case formatXLS:
+		return openXLSRows(br, sheet)
+	}
+	return nil, fmt.Errorf("fayl formati noma'lum (faqat .xlsx yoki .xls)")
+}
+
+// xlsxRows - excelize oqimli iteratori
+type xlsxRows struct {
+	f    *excelize.File
+	rows *excelize.Rows
+}
+
+func openXLSXRows(r io.Reader, sheet string) (rowIterator, error) {
+	// OpenReader faylni (siqilgan holda) xotiraga o'qiydi - ZIP io.ReaderAt talab qiladi
+	f, err := excelize.OpenReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("fayl ochilmadi: %v", err)
+	}
+
+	sheetName := f.GetSheetName(0)
+	if sheet != "" {
+		if idx, _ := f.GetSheetIndex(sheet); idx < 0 {
+			f.Close()
+			return nil, fmt.Errorf("sheet topilmadi: %s", sheet)
+		}
+		sheetName = sheet
+	}
+	if sheetName == "" {
+		f.Close()
+		return nil, fmt.Errorf("sheet topilmadi")
+	}
+
+	rows, err := f.Rows(sheetName)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("qatorlar o'qilmadi: %v", err)
+	}
+	return &xlsxRows{f: f, rows: rows}, nil
+}
+
+func (x *xlsxRows) Next() bool                 { return x.rows.Next() }
+func (x *xlsxRows) Columns() ([]string, error) { return x.rows.Columns() }
+func (x *xlsxRows) Err() error                 { return x.rows.Error() }
+
+func (x *xlsxRows) Close() error {
+	x.rows.Close()
+	return x.f.Close()
+}
+
+// xlsRows - eski .xls formati; kutubxona sheetni to'liq o'qiydi, shuning uchun faqat indeks yuradi
+type xlsRows struct {
+	sheet *xls.WorkSheet
+	cur   int
+}
+
+func openXLSRows(r io.Reader, sheetName string) (rowIterator, error) {
+	// xls kutubxonasi io.ReadSeeker talab qiladi - fayl bir marta xotiraga o'qiladi
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	wb, err := xls.OpenReader(bytes.NewReader(data), "utf-8")
+	if err != nil || wb == nil {
+		return nil, fmt.Errorf("fayl ochilmadi: %v", err)
+	}
+
+	sheet := wb.GetSheet(0)
+	if sheetName != "" {
+		sheet = nil
+		for i := 0; i < wb.NumSheets(); i++ {
+			if s := wb.GetSheet(i); s != nil && s.Name == sheetName {
+				sheet = s
+				break
+			}
+		}
+	}
+	if sheet == nil {
+		return nil, fmt.Errorf("sheet topilmadi: %s", sheetName)
+	}
+
+	return &xlsRows{sheet: sheet, cur: -1}, nil
+}
+
+func (x *xlsRows) Next() bool {
+	x.cur++
+	return x.cur <= int(x.sheet.MaxRow)
+}
+
+func (x *xlsRows) Columns() ([]string, error) {
+	row := xlsRow(x.sheet, x.cur)
+	if row == nil {
+		return nil, nil
+	}
+
+	last := row.LastCol()
+	if last < row.FirstCol() {
+		return nil, nil
+	}
+	cells := make([]string, last+1)
+	for j := row.FirstCol(); j <= last; j++ {
+		cells[j] = row.Col(j)
+	}
+	return cells, nil
+}
+
+func (x *xlsRows) Err() error   { return nil }
+func (x *xlsRows) Close() error { return nil }
+
+// xlsRow - i-qator; chegaradan tashqarida yoki faylda yozuvi yo'q (bo'sh) qator uchun nil.
+// WorkSheet.Row yo'q qatorda nil pointer bilan panic qiladi, qator borligini tekshiradigan
+// ochiq metod esa yo'q - shuning uchun kutubxonaning qatorlar jadvali reflect bilan o'qiladi.
+func xlsRow(sheet *xls.WorkSheet, i int) *xls.Row {
+	if i < 0 || i > int(sheet.MaxRow) {
+		return nil
+	}
+	rows := reflect.ValueOf(sheet).Elem().FieldByName("rows")
+	if rows.Kind() != reflect.Map {
+		return nil
+	}
+	if row := rows.MapIndex(reflect.ValueOf(uint16(i))); !row.IsValid() || row.IsNil() {
+		return nil
+	}
+	return sheet.Row(i)
+}
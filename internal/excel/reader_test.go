@@ -0,0 +1,17 @@
+package excel
+
+import (
+	"testing"
+
+	"github.com/extrame/xls"
+)
+
+func TestXLSRowMissing(t *testing.T) {
+	// Qatorlar jadvali bo'sh - WorkSheet.Row bu yerda panic qilardi
+	sheet := &xls.WorkSheet{MaxRow: 3}
+	for _, i := range []int{-1, 0, 2, 3, 4} {
+		if row := xlsRow(sheet, i); row != nil {
+			t.Errorf("xlsRow(%d) = %v; kutilgan nil", i, row)
+		}
+	}
+}
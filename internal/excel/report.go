@@ -0,0 +1,70 @@
+package excel
+
+import (
+	"fmt"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// Muammoli qator turlari
+const (
+	IssueSkipped    = "skipped"    // parse qilinmadi, bazaga yozilmaydi
+	IssueDuplicate  = "duplicate"  // nom takrorlangan
+	IssueSuspicious = "suspicious" // karantinga tushadi, admin tasdiqlaydi
+)
+
+var issueLabels = map[string]string{
+	IssueSkipped:    "O'tkazildi",
+	IssueDuplicate:  "Dublikat",
+	IssueSuspicious: "Shubhali",
+}
+
+// RowIssue - fayldagi muammoli qator
+type RowIssue struct {
+	Row     int    `json:"row"` // fayldagi asl qator raqami, 1 dan
+	Kind    string `json:"kind"`
+	Reason  string `json:"reason"`
+	Content string `json:"content"`
+}
+
+// ErrorReport - muammoli qatorlar ro'yxati .xlsx ko'rinishida
+func (s *StagedImport) ErrorReport() ([]byte, error) {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	sheet := "Xatolar"
+	f.SetSheetName(f.GetSheetName(0), sheet)
+
+	headers := []interface{}{"Qator", "Holat", "Sabab", "Qator mazmuni"}
+	if err := f.SetSheetRow(sheet, "A1", &headers); err != nil {
+		return nil, err
+	}
+
+	bold, err := f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}})
+	if err != nil {
+		return nil, err
+	}
+	f.SetCellStyle(sheet, "A1", "D1", bold)
+	f.SetColWidth(sheet, "A", "A", 8)
+	f.SetColWidth(sheet, "B", "B", 14)
+	f.SetColWidth(sheet, "C", "C", 45)
+	f.SetColWidth(sheet, "D", "D", 80)
+	f.SetPanes(sheet, &excelize.Panes{Freeze: true, YSplit: 1, TopLeftCell: "A2", ActivePane: "bottomLeft"})
+
+	for i, issue := range s.Issues {
+		row := []interface{}{issue.Row, issueLabels[issue.Kind], issue.Reason, issue.Content}
+		if err := f.SetSheetRow(sheet, fmt.Sprintf("A%d", i+2), &row); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(s.Issues) > 0 {
+		f.AutoFilter(sheet, fmt.Sprintf("A1:D%d", len(s.Issues)+1), nil)
+	}
+
+	buf, err := f.WriteToBuffer()
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,46 @@
+package excel
+
+import "time"
+
+// ImportResult - Commit natijasi. Paket o'zi xabar yubormaydi - natijani chaqiruvchi
+// (Telegram bot, HTTP API, CLI) o'z formatida ko'rsatadi.
+type ImportResult struct {
+	ImportID      int
+	PharmacyID    int
+	FileName      string
+	Mode          string
+	Saved         int // bazaga yozilgan dorilar (karantindagilarsiz)
+	Inserted      int // shundan yangi qo'shilgan
+	Updated       int // shundan mavjudi yangilangan
+	Removed       int // faylda yo'q, o'chirilgan (replace rejimi)
+	Duplicates    int
+	Skipped       int
+	Issues        []RowIssue // o'tkazilgan, dublikat va shubhali qatorlar (sababi bilan)
+	CategoryStats map[string]int
+	Quarantined   []QuarantineItem // tekshiruvdan o'tmagan, admin qarorini kutayotgan dorilar
+	Diff          *ImportDiff      // upsert oldidagi holat bilan farq
+	Duration      time.Duration
+}
+
+// SkippedRows - parse qilinmagan qatorlar
+func (r *ImportResult) SkippedRows() []RowIssue {
+	var rows []RowIssue
+	for _, issue := range r.Issues {
+		if issue.Kind == IssueSkipped {
+			rows = append(rows, issue)
+		}
+	}
+	return rows
+}
+
+// Reporter - import jarayonini kuzatuvchi (masalan Telegram xabarini tahrirlash)
+type Reporter interface {
+	// Progress - har bir batch yozilgandan keyin: saqlangan va jami dorilar
+	Progress(saved, total int)
+}
+
+// ProgressFunc - oddiy funksiyani Reporter sifatida ishlatish
+type ProgressFunc func(saved, total int)
+
+// Progress - Reporter interfeysi
+func (f ProgressFunc) Progress(saved, total int) { f(saved, total) }
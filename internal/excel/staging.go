@@ -0,0 +1,164 @@
+package excel
+
+import (
+	"database/sql"
+	"sort"
+	"time"
+
+	"testuchun/internal/money"
+)
+
+// Import rejimlari
+const (
+	ModeMerge   = "merge"   // faqat qo'shish va yangilash
+	ModeReplace = "replace" // to'liq sinxron - faylda yo'q dorilar o'chiriladi
+)
+
+// ModeSettingKey - settings jadvalida dorixonaning standart import rejimi
+const ModeSettingKey = "import_mode"
+
+// StagedImportTTL - tasdiqlanmagan import shu vaqtdan keyin bekor bo'ladi
+const StagedImportTTL = 15 * time.Minute
+
+// previewSampleSize - ko'rsatiladigan o'zgarishlar soni
+const previewSampleSize = 10
+
+// StagedImport - parse qilingan, lekin hali bazaga yozilmagan import
+type StagedImport struct {
+	FileName      string
+	PharmacyID    int
+	Medicines     []Medicine
+	Skipped       int
+	Duplicates    int
+	Issues        []RowIssue // o'tkazilgan, dublikat va shubhali qatorlar
+	CategoryStats map[string]int
+	Mode          string // ModeMerge yoki ModeReplace
+	Checksum      string // fayl SHA-256
+	UploadedBy    int64  // yuklagan foydalanuvchi (Telegram ID)
+	CreatedAt     time.Time
+}
+
+// ImportPreview - import bazaga nima qilishini ko'rsatadi
+type ImportPreview struct {
+	New       int
+	Updated   int
+	Unchanged int
+	Unparsed  int
+	Missing   int              // faylda yo'q, replace rejimida o'chiriladi
+	Flagged   int              // tekshiruvdan o'tmaydi, karantinga tushadi
+	Changes   []MedicineChange // narx/miqdor o'zgarishlaridan namuna
+}
+
+// MedicineChange - bitta dorining eski va yangi qiymatlari
+type MedicineChange struct {
+	Kind     string // Change* (faqat diff hisobotida)
+	Name     string
+	OldPrice money.Money
+	NewPrice money.Money
+	OldCount int
+	NewCount int
+}
+
+// Expired - import tasdiqlash muddati o'tganmi
+func (s *StagedImport) Expired() bool {
+	return time.Since(s.CreatedAt) > StagedImportTTL
+}
+
+// Preview - mavjud dorilar bilan solishtirish (bazaga hech narsa yozilmaydi)
+func (s *StagedImport) Preview(db *sql.DB) (*ImportPreview, error) {
+	existing, err := loadExisting(db, s.PharmacyID)
+	if err != nil {
+		return nil, err
+	}
+
+	preview := &ImportPreview{Unparsed: s.Skipped}
+	var changes []MedicineChange
+	jump := LoadPriceJump(db, s.PharmacyID)
+
+	for _, med := range s.Medicines {
+		old, ok := existing[med.Name]
+		if checkAnomaly(med, old, ok, jump) != "" {
+			preview.Flagged++
+			continue
+		}
+		if !ok {
+			preview.New++
+			continue
+		}
+		if old.Price == med.Price && old.Count == med.Count {
+			preview.Unchanged++
+			continue
+		}
+
+		preview.Updated++
+		changes = append(changes, MedicineChange{
+			Name:     med.Name,
+			OldPrice: old.Price,
+			NewPrice: med.Price,
+			OldCount: old.Count,
+			NewCount: med.Count,
+		})
+	}
+
+	inFile := make(map[string]bool, len(s.Medicines))
+	for _, med := range s.Medicines {
+		inFile[med.Name] = true
+	}
+	for name := range existing {
+		if !inFile[name] {
+			preview.Missing++
+		}
+	}
+
+	// Avval narxi eng ko'p o'zgarganlar
+	sort.Slice(changes, func(i, j int) bool {
+		return absMoney(changes[i].NewPrice-changes[i].OldPrice) > absMoney(changes[j].NewPrice-changes[j].OldPrice)
+	})
+	if len(changes) > previewSampleSize {
+		changes = changes[:previewSampleSize]
+	}
+	preview.Changes = changes
+
+	return preview, nil
+}
+
+// LoadMode - dorixonaning standart import rejimi (sozlanmagan bo'lsa merge)
+func LoadMode(db *sql.DB, pharmacyID int) string {
+	var mode string
+	db.QueryRow("SELECT value FROM settings WHERE key = $1 AND pharmacy_id = $2", ModeSettingKey, pharmacyID).Scan(&mode)
+	if mode != ModeReplace {
+		return ModeMerge
+	}
+	return mode
+}
+
+// queryer - *sql.DB yoki *sql.Tx
+type queryer interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// loadExisting - dorixonadagi mavjud dorilar (nom -> dori)
+func loadExisting(db queryer, pharmacyID int) (map[string]Medicine, error) {
+	rows, err := db.Query("SELECT name, price, count FROM medicines WHERE pharmacy_id = $1", pharmacyID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	existing := make(map[string]Medicine)
+	for rows.Next() {
+		var med Medicine
+		if err := rows.Scan(&med.Name, &med.Price, &med.Count); err != nil {
+			return nil, err
+		}
+		existing[med.Name] = med
+	}
+	return existing, rows.Err()
+}
+
+func absMoney(n money.Money) money.Money {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
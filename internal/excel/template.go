@@ -0,0 +1,241 @@
+package excel
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+
+	"testuchun/internal/money"
+)
+
+// templateSheet - shablonning ma'lumot sheeti; import birinchi sheetni o'qiydi
+const templateSheet = "Dorilar"
+
+// templateRows - ma'lumot tekshiruvi qo'llaniladigan qatorlar soni
+const templateRows = 5000
+
+// templateColumns - shablon ustunlari; sarlavhalar headerAliases bo'yicha taniladi
+var templateColumns = []struct {
+	Header   string
+	Width    float64
+	Required bool
+}{
+	{"№", 6, true},
+	{"Наименование / Dori nomi", 45, true},
+	{"Количество / Miqdor", 14, true},
+	{"Цена / Narx", 14, true},
+	{"Производитель / Ishlab chiqaruvchi", 28, true},
+	{"Штрихкод / Shtrix-kod", 16, false},
+	{"Серия / Seriya", 14, false},
+	{"Срок годности / Yaroqlilik muddati", 18, false},
+	{"Категория / Kategoriya", 18, false},
+}
+
+// templateInstructions - yo'riqnoma sheeti (o'zbek va rus tillarida)
+var templateInstructions = []string{
+	"YO'RIQNOMA",
+	"",
+	"1. Dorilarni \"Dorilar\" sheetiga kiriting. Sarlavha qatorini o'zgartirmang va ustunlar tartibini saqlang.",
+	"2. № - tartib raqami (1, 2, 3, ...). Raqamsiz qatorlar (masalan \"Итого\") o'tkazib yuboriladi.",
+	"3. Majburiy ustunlar: №, Dori nomi, Miqdor, Narx, Ishlab chiqaruvchi.",
+	"4. Narx so'mda, tiyin bilan yozish mumkin: 12450,50. Miqdor - butun son.",
+	"5. Ixtiyoriy: shtrix-kod (EAN-13/EAN-8), seriya, yaroqlilik muddati (31.12.2026), kategoriya.",
+	"6. Bir dorining bir nechta partiyasi bo'lsa - har bir partiya alohida qatorda, nomi bir xil.",
+	"7. Kategoriya bo'sh bo'lsa dori nomidan avtomatik aniqlanadi.",
+	"8. Tayyor faylni botga yuboring.",
+	"",
+	"ИНСТРУКЦИЯ",
+	"",
+	"1. Заполните лист \"Dorilar\". Не меняйте строку заголовков и порядок столбцов.",
+	"2. № - порядковый номер (1, 2, 3, ...). Строки без номера (например \"Итого\") пропускаются.",
+	"3. Обязательные столбцы: №, Наименование, Количество, Цена, Производитель.",
+	"4. Цена в сумах, можно с тийинами: 12450,50. Количество - целое число.",
+	"5. Необязательно: штрихкод (EAN-13/EAN-8), серия, срок годности (31.12.2026), категория.",
+	"6. Если у препарата несколько партий - каждая партия отдельной строкой с одинаковым наименованием.",
+	"7. Если категория не указана, она определяется автоматически по наименованию.",
+	"8. Отправьте готовый файл боту.",
+}
+
+// Template - import shabloni .xlsx. pharmacyID > 0 bo'lsa dorixonaning joriy dorilari bilan to'ldiriladi
+// (har bir partiya alohida qatorda) - tahrirlab qayta yuklash mumkin.
+func Template(db *sql.DB, pharmacyID int) ([]byte, error) {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	f.SetSheetName(f.GetSheetName(0), templateSheet)
+
+	header := make([]interface{}, len(templateColumns))
+	for i, col := range templateColumns {
+		header[i] = col.Header
+		name, _ := excelize.ColumnNumberToName(i + 1)
+		f.SetColWidth(templateSheet, name, name, col.Width)
+	}
+	if err := f.SetSheetRow(templateSheet, "A1", &header); err != nil {
+		return nil, err
+	}
+
+	required, err := f.NewStyle(&excelize.Style{
+		Font: &excelize.Font{Bold: true},
+		Fill: excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"C6EFCE"}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	optional, err := f.NewStyle(&excelize.Style{
+		Font: &excelize.Font{Bold: true},
+		Fill: excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"EDEDED"}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i, col := range templateColumns {
+		cell, _ := excelize.CoordinatesToCellName(i+1, 1)
+		style := optional
+		if col.Required {
+			style = required
+		}
+		f.SetCellStyle(templateSheet, cell, cell, style)
+	}
+	f.SetPanes(templateSheet, &excelize.Panes{Freeze: true, YSplit: 1, TopLeftCell: "A2", ActivePane: "bottomLeft"})
+
+	// Narx va sana ko'rinishi - import ularni shu formatda o'qiydi
+	priceFormat := "0.00"
+	priceStyle, err := f.NewStyle(&excelize.Style{CustomNumFmt: &priceFormat})
+	if err != nil {
+		return nil, err
+	}
+	dateFormat := "dd.mm.yyyy"
+	dateStyle, err := f.NewStyle(&excelize.Style{CustomNumFmt: &dateFormat})
+	if err != nil {
+		return nil, err
+	}
+	textStyle, err := f.NewStyle(&excelize.Style{NumFmt: 49}) // "@" - shtrix-kod E+ ko'rinishiga o'tmasin
+	if err != nil {
+		return nil, err
+	}
+	last := templateRows + 1
+	f.SetCellStyle(templateSheet, "D2", fmt.Sprintf("D%d", last), priceStyle)
+	f.SetCellStyle(templateSheet, "F2", fmt.Sprintf("F%d", last), textStyle)
+	f.SetCellStyle(templateSheet, "H2", fmt.Sprintf("H%d", last), dateStyle)
+
+	if err := addTemplateValidation(f, last); err != nil {
+		return nil, err
+	}
+
+	if pharmacyID > 0 {
+		if err := fillTemplate(db, f, pharmacyID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := writeTemplateInstructions(f); err != nil {
+		return nil, err
+	}
+	f.SetActiveSheet(0)
+
+	buf, err := f.WriteToBuffer()
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// addTemplateValidation - raqamli ustunlarga noto'g'ri qiymat kiritilmasin
+func addTemplateValidation(f *excelize.File, last int) error {
+	rules := []struct {
+		Column string
+		Min    interface{}
+		Max    interface{}
+		Type   excelize.DataValidationType
+		Error  string
+	}{
+		{"A", 1, 1000000, excelize.DataValidationTypeWhole, "Tartib raqami - musbat butun son / Порядковый номер - целое положительное число"},
+		{"C", 0, MaxReasonableCount, excelize.DataValidationTypeWhole, fmt.Sprintf("Miqdor - 0 dan %d gacha butun son / Количество - целое число от 0 до %d", MaxReasonableCount, MaxReasonableCount)},
+		{"D", 0.01, 1000000000.0, excelize.DataValidationTypeDecimal, "Narx - musbat son / Цена - положительное число"},
+	}
+
+	for _, rule := range rules {
+		dv := excelize.NewDataValidation(true)
+		dv.Sqref = fmt.Sprintf("%s2:%s%d", rule.Column, rule.Column, last)
+		if err := dv.SetRange(rule.Min, rule.Max, rule.Type, excelize.DataValidationOperatorBetween); err != nil {
+			return err
+		}
+		dv.SetError(excelize.DataValidationErrorStyleStop, "Xato / Ошибка", rule.Error)
+		if err := f.AddDataValidation(templateSheet, dv); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fillTemplate - dorixonaning joriy dorilari; partiyali dori har bir partiyasi bilan alohida qatorda
+func fillTemplate(db *sql.DB, f *excelize.File, pharmacyID int) error {
+	rows, err := db.Query(`
+		SELECT m.name, COALESCE(b.count, m.count), m.price, COALESCE(m.manufacturer, ''),
+		       COALESCE(m.barcode, ''), COALESCE(b.series, ''), b.expiry_date, COALESCE(m.category, '')
+		FROM medicines m
+		LEFT JOIN medicine_batches b ON b.pharmacy_id = m.pharmacy_id AND b.name = m.name
+		WHERE m.pharmacy_id = $1
+		ORDER BY m.name, b.expiry_date NULLS LAST, b.series
+	`, pharmacyID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	num := 0
+	for rows.Next() {
+		var name, manufacturer, barcode, series, category string
+		var count int
+		var price money.Money
+		var expiry sql.NullTime
+		if err := rows.Scan(&name, &count, &price, &manufacturer, &barcode, &series, &expiry, &category); err != nil {
+			return err
+		}
+
+		num++
+		var expiryCell interface{} = ""
+		if expiry.Valid {
+			expiryCell = time.Date(expiry.Time.Year(), expiry.Time.Month(), expiry.Time.Day(), 0, 0, 0, 0, time.UTC)
+		}
+		row := []interface{}{num, name, count, float64(price) / 100, manufacturer, barcode, series, expiryCell, category}
+		if err := f.SetSheetRow(templateSheet, fmt.Sprintf("A%d", num+1), &row); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// writeTemplateInstructions - yo'riqnoma sheeti (importda o'qilmaydi - faqat birinchi sheet)
+func writeTemplateInstructions(f *excelize.File) error {
+	sheet := "Yo'riqnoma"
+	if _, err := f.NewSheet(sheet); err != nil {
+		return err
+	}
+	f.SetColWidth(sheet, "A", "A", 110)
+
+	bold, err := f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true, Size: 12}})
+	if err != nil {
+		return err
+	}
+	for i, line := range templateInstructions {
+		cell := fmt.Sprintf("A%d", i+1)
+		if err := f.SetCellValue(sheet, cell, line); err != nil {
+			return err
+		}
+		if line == "YO'RIQNOMA" || line == "ИНСТРУКЦИЯ" {
+			f.SetCellStyle(sheet, cell, cell, bold)
+		}
+	}
+	return nil
+}
+
+// TemplateFileName - "shablon.xlsx" yoki "shablon_1_2026-10-16.xlsx"
+func TemplateFileName(pharmacyID int) string {
+	if pharmacyID == 0 {
+		return "shablon.xlsx"
+	}
+	return fmt.Sprintf("shablon_%d_%s.xlsx", pharmacyID, time.Now().Format("2006-01-02"))
+}
@@ -0,0 +1,54 @@
+package excel
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+
+	"github.com/extrame/xls"
+	"github.com/xuri/excelize/v2"
+)
+
+// WorkbookSheets - workbook ichidagi barcha sheet nomlari (.xlsx yoki .xls)
+func WorkbookSheets(data []byte) ([]string, error) {
+	switch detectFormat(data) {
+	case formatXLSX:
+		f, err := excelize.OpenReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("fayl ochilmadi: %v", err)
+		}
+		defer f.Close()
+		return f.GetSheetList(), nil
+	case formatXLS:
+		wb, err := xls.OpenReader(bytes.NewReader(data), "utf-8")
+		if err != nil || wb == nil {
+			return nil, fmt.Errorf("fayl ochilmadi: %v", err)
+		}
+		var sheets []string
+		for i := 0; i < wb.NumSheets(); i++ {
+			if sheet := wb.GetSheet(i); sheet != nil {
+				sheets = append(sheets, sheet.Name)
+			}
+		}
+		return sheets, nil
+	}
+	return nil, fmt.Errorf("fayl formati noma'lum (faqat .xlsx yoki .xls)")
+}
+
+// PrepareSheet - workbookning bitta sheetini berilgan dorixona uchun parse qilish.
+// Dorixona profilining qolgan sozlamalari saqlanadi, faqat sheet almashtiriladi.
+func PrepareSheet(db *sql.DB, fileName string, data []byte, sheet, phone, address string, pharmacyID int) (*StagedImport, error) {
+	profile, err := LoadProfile(db, pharmacyID)
+	if err != nil {
+		return nil, err
+	}
+
+	sheetProfile := ImportProfile{}
+	if profile != nil {
+		sheetProfile = *profile
+	}
+	sheetProfile.Sheet = sheet
+
+	name := fmt.Sprintf("%s [%s]", fileName, sheet)
+	return prepareExcel(db, name, bytes.NewReader(data), &sheetProfile, phone, address, pharmacyID)
+}
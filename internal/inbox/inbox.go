@@ -0,0 +1,181 @@
+package inbox
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"testuchun/internal/excel"
+)
+
+// Papkalar: <dir>/<pharmacy_id>/ ichiga tushgan fayl import qilinadi,
+// keyin <dir>/<pharmacy_id>/done/ yoki failed/ ga ko'chiriladi
+const (
+	DoneDir   = "done"
+	FailedDir = "failed"
+)
+
+// settleTime - fayl shu vaqt o'zgarmasa yozib bo'lingan hisoblanadi
+const settleTime = 3 * time.Second
+
+// rescanInterval - hodisa o'tkazib yuborilgan bo'lsa ham fayllar topiladi
+const rescanInterval = time.Minute
+
+// Handler - bitta faylni import qilish; xato bo'lsa fayl failed/ ga tushadi
+type Handler func(pharmacyID int, path string) error
+
+// Watcher - dorixona papkalarini kuzatuvchi
+type Watcher struct {
+	dir        string
+	pharmacies []int
+	handle     Handler
+	unmoved    map[string]unmovedFile // import qilingan, lekin ko'chirilmagan fayllar
+}
+
+// unmovedFile - ko'chirish xato bergan fayl; qayta import qilinmaydi, faqat ko'chirish takrorlanadi
+type unmovedFile struct {
+	modTime time.Time
+	target  string
+	err     error
+}
+
+// New - papkalarni yaratish (<dir>/<id>/done, <dir>/<id>/failed)
+func New(dir string, pharmacies []int, handle Handler) (*Watcher, error) {
+	for _, id := range pharmacies {
+		for _, sub := range []string{DoneDir, FailedDir} {
+			if err := os.MkdirAll(filepath.Join(dir, strconv.Itoa(id), sub), 0o755); err != nil {
+				return nil, fmt.Errorf("papka yaratilmadi: %v", err)
+			}
+		}
+	}
+	return &Watcher{dir: dir, pharmacies: pharmacies, handle: handle, unmoved: map[string]unmovedFile{}}, nil
+}
+
+// Run - kuzatishni boshlash (bloklaydi). Ishga tushganda mavjud fayllar ham import qilinadi.
+func (w *Watcher) Run() error {
+	fw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer fw.Close()
+
+	for _, id := range w.pharmacies {
+		if err := fw.Add(w.pharmacyDir(id)); err != nil {
+			return fmt.Errorf("papka kuzatilmadi: %v", err)
+		}
+	}
+
+	// Hodisadan keyin fayl yozilib bo'lishini kutish uchun qayta tekshiriladi
+	settle := time.NewTimer(0)
+	defer settle.Stop()
+	rescan := time.NewTicker(rescanInterval)
+	defer rescan.Stop()
+
+	for {
+		select {
+		case event, ok := <-fw.Events:
+			if !ok {
+				return nil
+			}
+			if event.Has(fsnotify.Create) || event.Has(fsnotify.Write) || event.Has(fsnotify.Rename) {
+				settle.Reset(settleTime)
+			}
+		case err, ok := <-fw.Errors:
+			if !ok {
+				return nil
+			}
+			fmt.Printf("⚠️ Inbox kuzatuvida xato: %v\n", err)
+		case <-settle.C:
+			if w.scan() {
+				settle.Reset(settleTime)
+			}
+		case <-rescan.C:
+			w.scan()
+		}
+	}
+}
+
+func (w *Watcher) pharmacyDir(id int) string {
+	return filepath.Join(w.dir, strconv.Itoa(id))
+}
+
+// scan - tayyor fayllarni import qilish; hali yozilayotgan fayl bo'lsa true
+func (w *Watcher) scan() (pending bool) {
+	for _, id := range w.pharmacies {
+		entries, err := os.ReadDir(w.pharmacyDir(id))
+		if err != nil {
+			fmt.Printf("⚠️ Inbox papkasi o'qilmadi: %v\n", err)
+			continue
+		}
+
+		for _, entry := range entries {
+			name := entry.Name()
+			if entry.IsDir() || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "~$") {
+				continue
+			}
+			if !excel.IsImportFile(name) {
+				continue
+			}
+
+			info, err := entry.Info()
+			if err != nil {
+				continue
+			}
+			if time.Since(info.ModTime()) < settleTime {
+				pending = true
+				continue
+			}
+
+			path := filepath.Join(w.pharmacyDir(id), name)
+			if u, ok := w.unmoved[path]; ok && u.modTime.Equal(info.ModTime()) {
+				// Bu fayl allaqachon import qilingan - faqat ko'chirishga qayta urinish
+				if w.move(id, path, u.target, u.err) {
+					delete(w.unmoved, path)
+				}
+				continue
+			}
+			delete(w.unmoved, path)
+
+			w.process(id, path, info.ModTime())
+		}
+	}
+	return pending
+}
+
+// process - import va natijaga qarab done/ yoki failed/ ga ko'chirish
+func (w *Watcher) process(pharmacyID int, path string, modTime time.Time) {
+	fmt.Printf("📥 Inbox: dorixona %d, %s\n", pharmacyID, filepath.Base(path))
+
+	target := DoneDir
+	err := w.handle(pharmacyID, path)
+	if err != nil {
+		target = FailedDir
+		fmt.Printf("❌ Inbox import xato: %v\n", err)
+	}
+
+	if !w.move(pharmacyID, path, target, err) {
+		// Fayl papkada qoladi - har bir skanerda qayta import qilinmasligi uchun eslab qolinadi
+		w.unmoved[path] = unmovedFile{modTime: modTime, target: target, err: err}
+	}
+}
+
+// move - faylni done/ yoki failed/ ga ko'chirish (xato bo'lsa yoniga .error.txt)
+func (w *Watcher) move(pharmacyID int, path, target string, importErr error) bool {
+	// Bir xil nomli fayllar ustma-ust yozilmasligi uchun vaqt belgisi qo'shiladi
+	dest := filepath.Join(w.pharmacyDir(pharmacyID), target,
+		time.Now().Format("20060102_150405")+"_"+filepath.Base(path))
+	if moveErr := os.Rename(path, dest); moveErr != nil {
+		fmt.Printf("❌ Fayl %s/ ga ko'chirilmadi, qayta import qilinmaydi: %v\n", target, moveErr)
+		return false
+	}
+
+	if importErr != nil {
+		os.WriteFile(dest+".error.txt", []byte(importErr.Error()+"\n"), 0o644)
+	}
+	return true
+}
@@ -0,0 +1,178 @@
+package money
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Money - so'mdagi summa, tiyinlarda saqlanadi (1 so'm = 100 tiyin).
+// float ishlatilmaydi - kasr qism yo'qolmaydi.
+type Money int64
+
+// FromSom - butun so'mdan Money
+func FromSom(som int64) Money {
+	return Money(som * 100)
+}
+
+// Som - butun so'm qismi (tiyinlar tashlanadi)
+func (m Money) Som() int64 {
+	return int64(m) / 100
+}
+
+// Parse - "12 450,50", "1,234.50", "12.450" kabi matnni o'qish.
+// decimal "," yoki "." bo'lsa kasr ajratuvchi shu, ikkinchisi minglik ajratuvchi;
+// bo'sh bo'lsa ajratuvchi qiymatdan aniqlanadi.
+func Parse(s, decimal string) (Money, error) {
+	s = strings.TrimSpace(s)
+	for _, sep := range []string{" ", " ", " ", "'", "’"} {
+		s = strings.ReplaceAll(s, sep, "")
+	}
+	if s == "" {
+		return 0, fmt.Errorf("summa bo'sh")
+	}
+
+	negative := false
+	if strings.HasPrefix(s, "-") {
+		negative = true
+		s = s[1:]
+	}
+
+	if decimal == "" {
+		decimal = detectDecimal(s)
+	}
+	switch decimal {
+	case ".":
+		s = strings.ReplaceAll(s, ",", "")
+	case ",":
+		s = strings.ReplaceAll(s, ".", "")
+		s = strings.ReplaceAll(s, ",", ".")
+	default:
+		s = strings.ReplaceAll(s, ",", "")
+		s = strings.ReplaceAll(s, ".", "")
+	}
+
+	whole, frac, _ := strings.Cut(s, ".")
+	if whole == "" {
+		whole = "0"
+	}
+	som, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("noto'g'ri summa: %s", s)
+	}
+
+	// Kasr qism 2 xonagacha yaxlitlanadi
+	tiyin := int64(0)
+	if frac != "" {
+		if _, err := strconv.ParseUint(frac, 10, 64); err != nil {
+			return 0, fmt.Errorf("noto'g'ri summa: %s", s)
+		}
+		frac += "00"
+		tiyin, _ = strconv.ParseInt(frac[:2], 10, 64)
+		if frac[2] >= '5' {
+			tiyin++
+		}
+	}
+
+	m := Money(som*100 + tiyin)
+	if negative {
+		m = -m
+	}
+	return m, nil
+}
+
+// detectDecimal - kasr ajratuvchini aniqlash ("" = kasr qism yo'q).
+// Ikkala belgi bo'lsa oxirgisi kasr; bitta belgidan keyin aniq 3 raqam bo'lsa minglik
+// (butun qism 0 bo'lmasa - "0,125" kasr).
+func detectDecimal(s string) string {
+	lastComma := strings.LastIndex(s, ",")
+	lastDot := strings.LastIndex(s, ".")
+
+	switch {
+	case lastComma >= 0 && lastDot >= 0:
+		if lastComma > lastDot {
+			return ","
+		}
+		return "."
+	case lastComma >= 0:
+		if strings.Count(s, ",") == 1 && (len(s)-lastComma-1 != 3 || strings.Trim(s[:lastComma], "0") == "") {
+			return ","
+		}
+	case lastDot >= 0:
+		if strings.Count(s, ".") == 1 && (len(s)-lastDot-1 != 3 || strings.Trim(s[:lastDot], "0") == "") {
+			return "."
+		}
+	}
+	return ""
+}
+
+// String - bazaga yozish formati ("12450.50")
+func (m Money) String() string {
+	sign := ""
+	if m < 0 {
+		sign = "-"
+		m = -m
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, int64(m)/100, int64(m)%100)
+}
+
+// Format - foydalanuvchiga ko'rsatish: "12 450,50" (tiyin bo'lmasa "12 450")
+func (m Money) Format() string {
+	sign := ""
+	if m < 0 {
+		sign = "-"
+		m = -m
+	}
+
+	digits := strconv.FormatInt(int64(m)/100, 10)
+	var b strings.Builder
+	for i, d := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteRune(d)
+	}
+
+	if tiyin := int64(m) % 100; tiyin != 0 {
+		return fmt.Sprintf("%s%s,%02d", sign, b.String(), tiyin)
+	}
+	return sign + b.String()
+}
+
+// Value - database/sql uchun (NUMERIC ustunga matn sifatida yoziladi)
+func (m Money) Value() (driver.Value, error) {
+	return m.String(), nil
+}
+
+// Scan - NUMERIC yoki INT ustundan o'qish
+func (m *Money) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*m = 0
+		return nil
+	case int64:
+		*m = FromSom(v)
+		return nil
+	case float64:
+		*m = Money(v*100 + 0.5)
+		if v < 0 {
+			*m = Money(v*100 - 0.5)
+		}
+		return nil
+	case []byte:
+		return m.scanString(string(v))
+	case string:
+		return m.scanString(v)
+	}
+	return fmt.Errorf("money: %T turini o'qib bo'lmaydi", src)
+}
+
+func (m *Money) scanString(s string) error {
+	parsed, err := Parse(s, ".")
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
@@ -0,0 +1,124 @@
+package money
+
+import (
+	"database/sql/driver"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in      string
+		decimal string
+		want    Money
+		wantErr bool
+	}{
+		{"12 450,50", "", 1245050, false},
+		{"12 450", "", 1245000, false},
+		{"1,234.50", "", 123450, false},
+		{"1.234,50", "", 123450, false},
+		{"1234.5", "", 123450, false},
+		{"12,5", "", 1250, false},
+		{"0,125", "", 13, false},
+		{"1,234", "", 123400, false}, // noaniq: 3 raqam - minglik ajratuvchi
+		{"1,234", ",", 123, false},   // sozlamada kasr "," bo'lsa kasr
+		{"1.234.567", "", 123456700, false},
+		{"12.450", ",", 1245000, false},
+		{"-12,50", "", -1250, false},
+		{"-1 000", "", -100000, false},
+		{"12,345", ".", 1234500, false},
+		{"0.005", ".", 1, false}, // 2 xonagacha yaxlitlanadi
+		{"", "", 0, true},
+		{"   ", "", 0, true},
+		{"abc", "", 0, true},
+		{"12,5x", "", 0, true},
+		{"1.2.3,4x", "", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := Parse(tt.in, tt.decimal)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("Parse(%q, %q) = %d, xato kutilgan edi", tt.in, tt.decimal, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Parse(%q, %q) xato: %v", tt.in, tt.decimal, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Parse(%q, %q) = %d, kutilgan %d", tt.in, tt.decimal, got, tt.want)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		in   Money
+		want string
+	}{
+		{0, "0"},
+		{1245050, "12 450,50"},
+		{1245000, "12 450"},
+		{123456789, "1 234 567,89"},
+		{-1250, "-12,50"},
+		{5, "0,05"},
+	}
+	for _, tt := range tests {
+		if got := tt.in.Format(); got != tt.want {
+			t.Errorf("Money(%d).Format() = %q, kutilgan %q", int64(tt.in), got, tt.want)
+		}
+	}
+}
+
+func TestValueScanRoundTrip(t *testing.T) {
+	for _, m := range []Money{0, 1, 99, 1245050, -1250, FromSom(1000000)} {
+		v, err := m.Value()
+		if err != nil {
+			t.Fatalf("Value(%d): %v", int64(m), err)
+		}
+
+		// NUMERIC ustun lib/pq orqali []byte bo'lib qaytadi
+		var fromBytes Money
+		if err := fromBytes.Scan([]byte(v.(string))); err != nil {
+			t.Fatalf("Scan(%q): %v", v, err)
+		}
+		if fromBytes != m {
+			t.Errorf("round trip %d -> %q -> %d", int64(m), v, int64(fromBytes))
+		}
+
+		// Parse(Format()) ham qiymatni saqlaydi
+		parsed, err := Parse(m.Format(), ",")
+		if err != nil || parsed != m {
+			t.Errorf("Parse(Format(%d)) = %d, %v", int64(m), int64(parsed), err)
+		}
+	}
+}
+
+func TestScanTypes(t *testing.T) {
+	tests := []struct {
+		src  driver.Value
+		want Money
+	}{
+		{nil, 0},
+		{int64(12450), 1245000},
+		{float64(12.5), 1250},
+		{float64(-12.5), -1250},
+		{"12450.50", 1245050},
+	}
+	for _, tt := range tests {
+		var m Money
+		if err := m.Scan(tt.src); err != nil {
+			t.Errorf("Scan(%v): %v", tt.src, err)
+			continue
+		}
+		if m != tt.want {
+			t.Errorf("Scan(%v) = %d, kutilgan %d", tt.src, int64(m), int64(tt.want))
+		}
+	}
+
+	var m Money
+	if err := m.Scan(true); err == nil {
+		t.Error("Scan(bool) xato qaytarishi kerak")
+	}
+}
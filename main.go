@@ -1,18 +1,30 @@
 package main
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
 	"database/sql"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"html"
+	"io"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"syscall"
 	"time"
 
+	"testuchun/internal/barcode"
 	"testuchun/internal/excel"
+	"testuchun/internal/inbox"
+	"testuchun/internal/money"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"github.com/joho/godotenv"
@@ -30,6 +42,39 @@ var adminPharmacy = make(map[int64]int) // userID -> pharmacy_id
 // Super admin uchun upload session (keyingi Excel faylni qaysi dorixonaga yuklash)
 var uploadSession = make(map[int64]int) // userID -> pharmacy_id
 
+// pendingImport - admin tasdiqlashini kutayotgan import
+type pendingImport struct {
+	staged *excel.StagedImport
+	userID int64
+	chatID int64
+}
+
+// Preview qilingan, Confirm/Cancel kutayotgan importlar
+var pendingImports = make(map[string]*pendingImport) // importID -> import
+
+// Super admin uchun ko'p sheetli workbook session (/upload all)
+var workbookSession = make(map[int64]bool) // userID -> keyingi fayl workbook
+
+// pendingWorkbook - sheetlarni dorixonalarga moslash kutilayotgan workbook
+type pendingWorkbook struct {
+	fileName string
+	data     []byte
+	sheets   []string
+	mapping  map[string]int // sheet -> pharmacy_id (0 = o'tkazish)
+	userID   int64
+	created  time.Time
+	staged   []stagedSheet // Import bosilgandan keyin tasdiqlash kutayotgan sheetlar
+}
+
+// stagedSheet - preview qilingan sheet va uning pendingImports dagi ID si
+type stagedSheet struct {
+	sheet    string
+	importID string
+}
+
+// Sheet -> dorixona moslashini kutayotgan workbooklar
+var pendingWorkbooks = make(map[string]*pendingWorkbook) // workbookID -> workbook
+
 // translitToRussian - lotin harflarni kirillga o'giradi
 func translitToRussian(text string) string {
 	replacements := map[string]string{
@@ -113,617 +158,2291 @@ func updateSetting(db *sql.DB, key, value string, pharmacyID int) error {
 	return err
 }
 
-// getAllPharmacies - barcha dorixonalar ro'yxati
-func getAllPharmacies(db *sql.DB) map[int]string {
-	pharmacies := make(map[int]string)
-	
-	rows, err := db.Query(`
-		SELECT DISTINCT pharmacy_id, value 
-		FROM settings 
-		WHERE key = 'name' 
-		ORDER BY pharmacy_id
-	`)
-	if err != nil {
-		return pharmacies
+// deleteSetting - settings dan qiymatni o'chirish (dorixona bo'yicha)
+func deleteSetting(db *sql.DB, key string, pharmacyID int) error {
+	_, err := db.Exec("DELETE FROM settings WHERE key = $1 AND pharmacy_id = $2", key, pharmacyID)
+	return err
+}
+
+// resolvePharmacy - super admin uchun birinchi so'z dorixona raqami, oddiy admin uchun o'z dorixonasi
+func resolvePharmacy(userID int64, input string) (int, string, error) {
+	input = strings.TrimSpace(input)
+	if !isSuperAdmin(userID) {
+		return getPharmacyID(userID), input, nil
 	}
-	defer rows.Close()
 
-	for rows.Next() {
-		var id int
-		var name string
-		if err := rows.Scan(&id, &name); err == nil {
-			pharmacies[id] = name
-		}
+	parts := strings.SplitN(input, " ", 2)
+	pharmacyID, err := strconv.Atoi(parts[0])
+	if err != nil || pharmacyID < 1 || pharmacyID > 3 {
+		return 0, "", fmt.Errorf("dorixona raqami 1, 2 yoki 3 bo'lishi kerak")
 	}
 
-	return pharmacies
+	rest := ""
+	if len(parts) == 2 {
+		rest = strings.TrimSpace(parts[1])
+	}
+	return pharmacyID, rest, nil
 }
 
-func main() {
-	err := godotenv.Load()
-	if err != nil {
-		log.Fatal(".env topilmadi")
+// stageImport - importni tasdiqlash uchun saqlab qo'yish (eskirganlar tozalanadi)
+func stageImport(staged *excel.StagedImport, userID, chatID int64) string {
+	for id, p := range pendingImports {
+		if p.staged.Expired() {
+			delete(pendingImports, id)
+		}
 	}
 
-	botToken := os.Getenv("BOT_TOKEN")
-	
-	// Super admin va oddiy adminlarni o'qish
-	superAdminID, _ = strconv.ParseInt(os.Getenv("ADMIN_ID_1"), 10, 64)
-	admin2, _ := strconv.ParseInt(os.Getenv("ADMIN_ID_2"), 10, 64)
-	admin3, _ := strconv.ParseInt(os.Getenv("ADMIN_ID_3"), 10, 64)
-	admin4, _ := strconv.ParseInt(os.Getenv("ADMIN_ID_4"), 10, 64)
-	
-	adminIDs = []int64{admin2, admin3, admin4}
-	
-	// Har bir adminga dorixona biriktirish
-	adminPharmacy[admin2] = 1 // Dorixona 1
-	adminPharmacy[admin3] = 2 // Dorixona 2
-	adminPharmacy[admin4] = 3 // Dorixona 3
+	importID := strconv.FormatInt(time.Now().UnixNano(), 36)
+	pendingImports[importID] = &pendingImport{staged: staged, userID: userID, chatID: chatID}
+	return importID
+}
 
-	bot, err := tgbotapi.NewBotAPI(botToken)
-	if err != nil {
-		log.Fatal(err)
+// formatImportPreview - import natijasi oldindan ko'rinishi
+func formatImportPreview(staged *excel.StagedImport, preview *excel.ImportPreview) string {
+	var b strings.Builder
+	b.WriteString("🔎 <b>Import preview</b>\n\n")
+	b.WriteString(fmt.Sprintf("📁 Fayl: <code>%s</code>\n", html.EscapeString(staged.FileName)))
+	b.WriteString(fmt.Sprintf("🏪 Dorixona: <b>%d</b>\n\n", staged.PharmacyID))
+	b.WriteString(fmt.Sprintf("🆕 Yangi: <b>%d</b> ta\n", preview.New))
+	b.WriteString(fmt.Sprintf("✏️ Yangilanadi: <b>%d</b> ta\n", preview.Updated))
+	b.WriteString(fmt.Sprintf("➖ O'zgarmagan: <b>%d</b> ta\n", preview.Unchanged))
+	b.WriteString(fmt.Sprintf("⏭ Parse qilinmagan: <b>%d</b> ta\n", preview.Unparsed))
+	if staged.Duplicates > 0 {
+		b.WriteString(fmt.Sprintf("🔄 Dublikatlar: <b>%d</b> ta (oxirgi qiymat olinadi)\n", staged.Duplicates))
 	}
-
-	db := connectDB()
-	defer db.Close()
-
-	// Healthcheck endpoint (Railway uchun)
-	go func() {
-		http.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-			w.WriteHeader(http.StatusOK)
-			w.Write([]byte("OK"))
-		})
-		
-		http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-			w.WriteHeader(http.StatusOK)
-			w.Write([]byte("Pharmacy Bot is running"))
-		})
-		
-		port := os.Getenv("PORT")
-		if port == "" {
-			port = "8080"
+	b.WriteString(fmt.Sprintf("🗑 Faylda yo'q: <b>%d</b> ta (to'liq sinxronda o'chiriladi)\n", preview.Missing))
+	if preview.Flagged > 0 {
+		b.WriteString(fmt.Sprintf("🚧 Karantinga tushadi: <b>%d</b> ta (admin tasdiqlaydi)\n", preview.Flagged))
+	}
+	b.WriteString(fmt.Sprintf("\n⚙️ Standart rejim: <b>%s</b>\n", importModeName(staged.Mode)))
+
+	if len(preview.Changes) > 0 {
+		b.WriteString("\n📈 <b>O'zgarishlar (namuna):</b>\n")
+		for _, ch := range preview.Changes {
+			b.WriteString(fmt.Sprintf("• %s\n", html.EscapeString(ch.Name)))
+			if ch.OldPrice != ch.NewPrice {
+				b.WriteString(fmt.Sprintf("   💰 %s → %s so'm\n", ch.OldPrice.Format(), ch.NewPrice.Format()))
+			}
+			if ch.OldCount != ch.NewCount {
+				b.WriteString(fmt.Sprintf("   🧮 %d → %d dona\n", ch.OldCount, ch.NewCount))
+			}
 		}
-		
-		fmt.Printf("🌐 Health check server running on :%s\n", port)
-		if err := http.ListenAndServe(":"+port, nil); err != nil {
-			log.Printf("⚠️ Health check server error: %v\n", err)
+	}
+
+	if len(staged.CategoryStats) > 0 {
+		b.WriteString("\n🏷 <b>Kategoriyalar:</b>\n")
+		for cat, count := range staged.CategoryStats {
+			b.WriteString(fmt.Sprintf("• %s: %d ta\n", cat, count))
 		}
-	}()
+	}
 
-	// Graceful shutdown
-	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
-	
-	go func() {
-		<-c
-		fmt.Println("\n🛑 Bot to'xtatilmoqda...")
-		bot.StopReceivingUpdates()
-		db.Close()
-		fmt.Println("✅ Bot to'xtatildi")
-		os.Exit(0)
-	}()
+	b.WriteString(fmt.Sprintf("\n⏳ %d daqiqa ichida tasdiqlang", int(excel.StagedImportTTL.Minutes())))
+	return b.String()
+}
 
-	u := tgbotapi.NewUpdate(0)
-	u.Timeout = 60
-	updates := bot.GetUpdatesChan(u)
+// matchSheetPharmacy - sheet nomi bo'yicha dorixonani topish (nom yoki raqam mos kelsa)
+func matchSheetPharmacy(sheet string, pharmacies map[int]string) int {
+	normalized := strings.ToLower(strings.TrimSpace(sheet))
+	for id, name := range pharmacies {
+		if normalized == strings.ToLower(strings.TrimSpace(name)) || normalized == strconv.Itoa(id) {
+			return id
+		}
+	}
+	return 0
+}
 
-	fmt.Println("✅ Bot ishga tushdi...")
-	fmt.Printf("🤖 Bot username: @%s\n", bot.Self.UserName)
-	fmt.Printf("👑 Super Admin: %d\n", superAdminID)
-	fmt.Printf("👥 Dorixona Adminlari:\n")
-	for _, aid := range adminIDs {
-		fmt.Printf("   - %d (Dorixona %d)\n", aid, adminPharmacy[aid])
+// workbookMappingMessage - sheet -> dorixona moslash xabari va tugmalari
+func workbookMappingMessage(db *sql.DB, wbID string, wb *pendingWorkbook) (string, tgbotapi.InlineKeyboardMarkup) {
+	var b strings.Builder
+	b.WriteString("📚 <b>Ko'p sheetli workbook</b>\n\n")
+	b.WriteString(fmt.Sprintf("📁 Fayl: <code>%s</code>\n\n", html.EscapeString(wb.fileName)))
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for i, sheet := range wb.sheets {
+		target := "⏭ o'tkaziladi"
+		if pid := wb.mapping[sheet]; pid > 0 {
+			name := getSetting(db, "name", pid)
+			if name == "" {
+				name = fmt.Sprintf("Dorixona %d", pid)
+			}
+			target = "🏪 " + html.EscapeString(name)
+		}
+		b.WriteString(fmt.Sprintf("📄 <b>%s</b> → %s\n", html.EscapeString(sheet), target))
+
+		row := []tgbotapi.InlineKeyboardButton{}
+		for pid := 1; pid <= 3; pid++ {
+			label := strconv.Itoa(pid)
+			if wb.mapping[sheet] == pid {
+				label = "✅ " + label
+			}
+			row = append(row, tgbotapi.NewInlineKeyboardButtonData(label, fmt.Sprintf("wb_map:%s:%d:%d", wbID, i, pid)))
+		}
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData("⏭", fmt.Sprintf("wb_map:%s:%d:0", wbID, i)))
+		rows = append(rows, row)
 	}
 
-	for update := range updates {
-		if update.Message == nil {
+	b.WriteString("\nHar bir sheet uchun dorixona raqamini tanlang (⏭ - o'tkazish)")
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("✅ Import", "wb_import:"+wbID),
+		tgbotapi.NewInlineKeyboardButtonData("❌ Bekor qilish", "wb_cancel:"+wbID),
+	))
+	return b.String(), tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// stageWorkbook - moslangan sheetlarni parse qilib tasdiqlashga qo'yish (bazaga yozilmaydi).
+// Har bir sheet pendingImports ga tushadi, muammoli qatorlar hisoboti chatga yuboriladi.
+// Umumiy preview matni qaytariladi.
+func stageWorkbook(db *sql.DB, bot *tgbotapi.BotAPI, wb *pendingWorkbook, chatID int64) string {
+	var b strings.Builder
+	b.WriteString("🔎 <b>Workbook preview</b>\n\n")
+	b.WriteString(fmt.Sprintf("📁 Fayl: <code>%s</code>\n\n", html.EscapeString(wb.fileName)))
+
+	for _, sheet := range wb.sheets {
+		pharmacyID := wb.mapping[sheet]
+		b.WriteString(fmt.Sprintf("📄 <b>%s</b>", html.EscapeString(sheet)))
+		if pharmacyID == 0 {
+			b.WriteString(" → ⏭ o'tkaziladi\n\n")
 			continue
 		}
+		b.WriteString(fmt.Sprintf(" → Dorixona %d\n", pharmacyID))
 
-		userID := update.Message.From.ID
-
-		// /start komandasi
-		if update.Message.Text == "/start" {
-			// Barcha dorixonalarning ma'lumotlarini ko'rsatish
-			var welcomeMsg strings.Builder
-			welcomeMsg.WriteString("🏥 <b>Dorixonalar tarmog'iga xush kelibsiz!</b>\n\n")
-			welcomeMsg.WriteString("💊 <b>Dori qidirish:</b>\n")
-			welcomeMsg.WriteString("Qidirmoqchi bo'lgan doringiz nomini yozing\n\n")
-			
-			pharmacies := getAllPharmacies(db)
-			if len(pharmacies) > 0 {
-				welcomeMsg.WriteString("📍 <b>Bizning filiallar:</b>\n\n")
-				for id := 1; id <= 3; id++ {
-					name := getSetting(db, "name", id)
-					phone := getSetting(db, "phone", id)
-					address := getSetting(db, "address", id)
-					
-					if name == "" {
-						name = fmt.Sprintf("Dorixona %d", id)
-					}
-					
-					welcomeMsg.WriteString(fmt.Sprintf("🏪 <b>%s</b>\n", name))
-					if phone != "" {
-						welcomeMsg.WriteString(fmt.Sprintf("📞 %s\n", phone))
-					}
-					if address != "" {
-						welcomeMsg.WriteString(fmt.Sprintf("📍 %s\n", address))
-					}
-					welcomeMsg.WriteString("\n")
-				}
-			}
-			
-			welcomeMsg.WriteString("✍️ Dori nomini yozing va qidiruvni boshlang!")
-			
-			msg := tgbotapi.NewMessage(update.Message.Chat.ID, welcomeMsg.String())
-			msg.ParseMode = "HTML"
-			bot.Send(msg)
+		phone := getSetting(db, "phone", pharmacyID)
+		address := getSetting(db, "address", pharmacyID)
+		if phone == "" || address == "" {
+			b.WriteString("❌ Telefon yoki manzil kiritilmagan\n\n")
 			continue
 		}
 
-		// /help komandasi
-		if update.Message.Text == "/help" {
-			helpMsg := 
-				"📖 <b>Yordam</b>\n\n" +
-				"🔍 <b>Qidirish:</b>\n" +
-				"Dori nomini rus yoki lotin harflarida yozing\n\n" +
-				"💡 <b>Maslahatlar:</b>\n" +
-				"• To'liq nom yozmasangiz ham bo'ladi\n" +
-				"• Katta-kichik harf farqi yo'q\n" +
-				"• Bir necha so'z bilan qidiring\n\n" +
-				"📋 Qidiruvda barcha filiallardan natija chiqadi"
-			
-			msg := tgbotapi.NewMessage(update.Message.Chat.ID, helpMsg)
-			msg.ParseMode = "HTML"
-			bot.Send(msg)
+		staged, err := excel.PrepareSheet(db, wb.fileName, wb.data, sheet, phone, address, pharmacyID)
+		if err == nil && len(staged.Medicines) == 0 {
+			err = emptyImportError(staged)
+		}
+		var preview *excel.ImportPreview
+		if err == nil {
+			preview, err = staged.Preview(db)
+		}
+		if err != nil {
+			fmt.Println("error:", err)
+			b.WriteString(fmt.Sprintf("❌ Xato: %s\n\n", html.EscapeString(err.Error())))
 			continue
 		}
 
-		// /ahad - MA'LUMOTLARNI O'CHIRISH
-		if update.Message.Text == "/ahad" {
-			if !isAdmin(userID) {
-				bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "❌ Siz admin emassiz"))
-				continue
-			}
+		staged.UploadedBy = wb.userID
+		wb.staged = append(wb.staged, stagedSheet{sheet: sheet, importID: stageImport(staged, wb.userID, chatID)})
 
-			var confirmMsg string
-			
-			if isSuperAdmin(userID) {
-				// Super admin - dorixona tanlash kerak
-				confirmMsg = 
-					"⚠️ <b>DIQQAT!</b>\n\n" +
-					"Qaysi dorixonaning ma'lumotlarini o'chirmoqchisiz?\n\n" +
-					"<code>/ahad 1</code> - Dorixona 1 ning barcha dorilarini o'chirish\n" +
-					"<code>/ahad 2</code> - Dorixona 2 ning barcha dorilarini o'chirish\n" +
-					"<code>/ahad 3</code> - Dorixona 3 ning barcha dorilarini o'chirish\n" +
-					"<code>/ahad all</code> - BARCHA dorixonalarning ma'lumotlarini o'chirish\n\n" +
-					"⚠️ Bu amaliyot QAYTARIB BO'LMAYDI!"
-			} else {
-				// Oddiy admin - faqat o'z dorixonasi
-				pharmacyID := getPharmacyID(userID)
-				pharmacyName := getSetting(db, "name", pharmacyID)
-				if pharmacyName == "" {
-					pharmacyName = fmt.Sprintf("Dorixona %d", pharmacyID)
-				}
-				
-				confirmMsg = fmt.Sprintf(
-					"⚠️ <b>DIQQAT!</b>\n\n"+
-					"Siz <b>%s</b> ning barcha dorilarini o'chirmoqchisiz\n\n"+
-					"🗑 Barcha dorilar o'chiriladi\n\n"+
-					"Bu amaliyotni <b>QAYTARIB BO'LMAYDI!</b>\n\n"+
-					"Davom etish uchun:\n"+
-					"<code>/ahad_confirm</code>\n\n"+
-					"Bekor qilish uchun:\n"+
-					"<code>/cancel</code>",
-					pharmacyName,
-				)
-			}
-			
-			msg := tgbotapi.NewMessage(update.Message.Chat.ID, confirmMsg)
-			msg.ParseMode = "HTML"
-			bot.Send(msg)
+		b.WriteString(fmt.Sprintf("🆕 %d | ✏️ %d | ➖ %d | ⏭ %d | 🗑 %d",
+			preview.New, preview.Updated, preview.Unchanged, preview.Unparsed, preview.Missing))
+		if preview.Flagged > 0 {
+			b.WriteString(fmt.Sprintf(" | 🚧 %d", preview.Flagged))
+		}
+		b.WriteString(fmt.Sprintf("\n⚙️ Standart rejim: %s\n\n", importModeName(staged.Mode)))
+
+		base := strings.TrimSuffix(wb.fileName, filepath.Ext(wb.fileName)) + "_" + sheet
+		sendIssueReport(bot, chatID, staged, base)
+	}
+
+	b.WriteString("🆕 yangi | ✏️ yangilanadi | ➖ o'zgarmagan | ⏭ parse qilinmagan | 🗑 to'liq sinxronda o'chiriladi | 🚧 karantin\n")
+	b.WriteString(fmt.Sprintf("\n⏳ %d daqiqa ichida tasdiqlang", int(excel.StagedImportTTL.Minutes())))
+	return b.String()
+}
+
+// commitWorkbook - tasdiqlangan sheetlarni tanlangan rejimda saqlash, umumiy natija qaytariladi.
+// Har bir sheet natijasi (o'zgarishlar hisoboti bilan) alohida yuboriladi.
+func commitWorkbook(db *sql.DB, bot *tgbotapi.BotAPI, wb *pendingWorkbook, mode string, status *importStatus) string {
+	var b strings.Builder
+	b.WriteString("📚 <b>Workbook yuklandi</b>\n\n")
+	b.WriteString(fmt.Sprintf("📁 Fayl: <code>%s</code>\n⚙️ Rejim: <b>%s</b>\n\n", html.EscapeString(wb.fileName), importModeName(mode)))
+
+	total := 0
+	for i, s := range wb.staged {
+		p, ok := pendingImports[s.importID]
+		delete(pendingImports, s.importID)
+		b.WriteString(fmt.Sprintf("📄 <b>%s</b>", html.EscapeString(s.sheet)))
+		if !ok || p.staged.Expired() {
+			b.WriteString(" → ⌛ muddati o'tdi\n\n")
+			continue
+		}
+		b.WriteString(fmt.Sprintf(" → Dorixona %d\n", p.staged.PharmacyID))
+
+		progress := fmt.Sprintf("💾 %s (%d/%d) saqlanmoqda", html.EscapeString(s.sheet), i+1, len(wb.staged))
+		status.Update(progress + "...")
+		p.staged.Mode = mode
+		res, err := p.staged.Commit(db, excel.ProgressFunc(func(saved, count int) {
+			status.Update(fmt.Sprintf("%s: %d/%d", progress, saved, count))
+		}))
+		if err != nil {
+			fmt.Println("error:", err)
+			b.WriteString(fmt.Sprintf("❌ Xato: %s\n\n", html.EscapeString(err.Error())))
 			continue
 		}
+		sendImportResult(bot, p.chatID, res)
 
-		// /ahad 1, /ahad 2, /ahad 3, /ahad all - Super admin uchun dorixona tanlash
-		if strings.HasPrefix(update.Message.Text, "/ahad ") {
-			if !isSuperAdmin(userID) {
-				bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "❌ Bu komanda faqat super admin uchun"))
-				continue
-			}
+		total += res.Saved
+		b.WriteString(fmt.Sprintf("✅ Saqlandi: <b>%d</b> | ⏭ %d | 🔄 %d", res.Saved, res.Skipped, res.Duplicates))
+		if res.Mode == excel.ModeReplace {
+			b.WriteString(fmt.Sprintf(" | 🗑 %d", res.Removed))
+		}
+		if len(res.Quarantined) > 0 {
+			b.WriteString(fmt.Sprintf(" | 🚧 %d", len(res.Quarantined)))
+		}
+		if res.ImportID > 0 {
+			b.WriteString(fmt.Sprintf("\n🆔 Import: #%d", res.ImportID))
+		}
+		b.WriteString("\n\n")
+	}
 
-			parts := strings.Split(strings.TrimSpace(update.Message.Text), " ")
-			if len(parts) != 2 {
-				bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "❌ Noto'g'ri format"))
-				continue
+	b.WriteString(fmt.Sprintf("📈 Jami saqlandi: <b>%d</b> ta dori", total))
+	return b.String()
+}
+
+// sendIssueReport - muammoli qatorlar hisobotini (xlsx) chatga yuborish, muammo bo'lmasa hech narsa
+func sendIssueReport(bot *tgbotapi.BotAPI, chatID int64, staged *excel.StagedImport, baseName string) {
+	if len(staged.Issues) == 0 {
+		return
+	}
+	report, err := staged.ErrorReport()
+	if err != nil {
+		fmt.Println("error:", err)
+		return
+	}
+	doc := tgbotapi.NewDocument(chatID, tgbotapi.FileBytes{
+		Name:  "xatolar_" + baseName + ".xlsx",
+		Bytes: report,
+	})
+	doc.Caption = fmt.Sprintf("⚠️ Muammoli qatorlar: %d ta\nFaylni tuzatib qayta yuborishingiz mumkin", len(staged.Issues))
+	bot.Send(doc)
+}
+
+// formatImportResult - import natijasi Telegram xabari (HTML)
+func formatImportResult(res *excel.ImportResult) string {
+	message := fmt.Sprintf(
+		"📊 <b>Fayl yuklandi</b>\n\n"+
+			"✅ Saqlandi: <b>%d</b> ta dori (🆕 %d yangi, ♻️ %d yangilandi)\n"+
+			"📁 Fayl: <code>%s</code>",
+		res.Saved, res.Inserted, res.Updated, html.EscapeString(res.FileName),
+	)
+
+	if res.Duplicates > 0 {
+		message += fmt.Sprintf("\n🔄 Dublikatlar: <b>%d</b> ta (oxirgi qiymat saqlandi)", res.Duplicates)
+	}
+
+	if skipped := res.SkippedRows(); len(skipped) > 0 {
+		message += fmt.Sprintf("\n⏭ O'tkazildi: <b>%d</b> ta qator", len(skipped))
+		for i, issue := range skipped {
+			if i == 3 {
+				message += fmt.Sprintf("\n  ... va yana %d ta", len(skipped)-3)
+				break
 			}
+			message += fmt.Sprintf("\n  • %d-qator: %s", issue.Row, html.EscapeString(issue.Reason))
+		}
+	}
 
-			target := parts[1]
-			var confirmMsg string
+	if len(res.Quarantined) > 0 {
+		message += fmt.Sprintf("\n🚧 Karantinda: <b>%d</b> ta (tekshirish: /quarantine)", len(res.Quarantined))
+	}
 
-			if target == "all" {
-				// Barcha dorixonalarni o'chirish
-				uploadSession[userID] = -1 // -1 = barcha dorixonalar
+	if res.Mode == excel.ModeReplace {
+		message += fmt.Sprintf("\n🗑 O'chirildi: <b>%d</b> ta (faylda yo'q)", res.Removed)
+	}
+
+	if len(res.CategoryStats) > 0 {
+		cats := make([]string, 0, len(res.CategoryStats))
+		for cat := range res.CategoryStats {
+			cats = append(cats, cat)
+		}
+		sort.Slice(cats, func(i, j int) bool { return res.CategoryStats[cats[i]] > res.CategoryStats[cats[j]] })
+		message += "\n\n📂 <b>Kategoriyalar:</b>"
+		for _, cat := range cats {
+			message += fmt.Sprintf("\n  %s: %d ta", html.EscapeString(cat), res.CategoryStats[cat])
+		}
+	}
+
+	if res.Diff != nil {
+		message += "\n\n" + res.Diff.Summary(res.Mode == excel.ModeReplace)
+	}
+
+	message += fmt.Sprintf("\n\n⏱ Vaqt: %.1f s", res.Duration.Seconds())
+	if res.ImportID > 0 {
+		message += fmt.Sprintf("\n🆔 Import: <b>#%d</b>\n↩️ Bekor qilish: <code>/rollback %d</code>", res.ImportID, res.ImportID)
+	}
+	return message
+}
+
+// sendImportResult - natija xabari va o'zgarishlar hisoboti (xlsx) chatga
+func sendImportResult(bot *tgbotapi.BotAPI, chatID int64, res *excel.ImportResult) {
+	if res.ImportID == 0 {
+		return // fayl bo'sh - bazaga hech narsa yozilmadi
+	}
+
+	msg := tgbotapi.NewMessage(chatID, formatImportResult(res))
+	msg.ParseMode = "HTML"
+	if _, err := bot.Send(msg); err != nil {
+		fmt.Printf("⚠️ Telegram xabar yuborilmadi: %v\n", err)
+	}
+
+	// To'liq o'zgarishlar ro'yxati fayl sifatida
+	if res.Diff == nil || res.Diff.Empty() {
+		return
+	}
+	report, err := res.Diff.Report()
+	if err != nil {
+		fmt.Printf("⚠️ O'zgarishlar hisoboti tuzilmadi: %v\n", err)
+		return
+	}
+	doc := tgbotapi.NewDocument(chatID, tgbotapi.FileBytes{Name: fmt.Sprintf("ozgarishlar_%d.xlsx", res.ImportID), Bytes: report})
+	doc.Caption = fmt.Sprintf("📊 Import #%d o'zgarishlari", res.ImportID)
+	if _, err := bot.Send(doc); err != nil {
+		fmt.Printf("⚠️ O'zgarishlar hisoboti yuborilmadi: %v\n", err)
+	}
+}
+
+// quarantineListSize - /quarantine xabarida ko'rsatiladigan dorilar
+const quarantineListSize = 10
+
+// canManagePharmacy - admin shu dorixonani boshqara oladimi
+func canManagePharmacy(userID int64, pharmacyID int) bool {
+	return isSuperAdmin(userID) || (isAdmin(userID) && getPharmacyID(userID) == pharmacyID)
+}
+
+// quarantineMessage - karantindagi dorilar ro'yxati va tasdiqlash/rad etish tugmalari
+func quarantineMessage(db *sql.DB, pharmacyID int) (string, *tgbotapi.InlineKeyboardMarkup, error) {
+	items, total, err := excel.ListQuarantine(db, pharmacyID, quarantineListSize)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("🚧 <b>Karantin - Dorixona %d</b>\n\n", pharmacyID))
+	if total == 0 {
+		b.WriteString("✅ Tekshirishni kutayotgan dorilar yo'q\n")
+		b.WriteString(fmt.Sprintf("\n⚙️ Narx sakrashi chegarasi: <b>%d%%</b>", excel.LoadPriceJump(db, pharmacyID)))
+		return b.String(), nil, nil
+	}
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for i, item := range items {
+		med := item.Medicine
+		b.WriteString(fmt.Sprintf("<b>%d. %s</b>\n", i+1, html.EscapeString(med.Name)))
+		if item.Existed {
+			b.WriteString(fmt.Sprintf("   💰 %s → %s so'm\n", item.OldPrice.Format(), med.Price.Format()))
+			b.WriteString(fmt.Sprintf("   🧮 %d → %d dona\n", item.OldCount, med.Count))
+		} else {
+			b.WriteString(fmt.Sprintf("   🆕 💰 %s so'm, 🧮 %d dona\n", med.Price.Format(), med.Count))
+		}
+		b.WriteString(fmt.Sprintf("   ⚠️ %s\n", html.EscapeString(item.Reason)))
+		if item.ImportID > 0 {
+			b.WriteString(fmt.Sprintf("   🆔 Import #%d\n", item.ImportID))
+		}
+		b.WriteString("\n")
+
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("✅ %d", i+1), fmt.Sprintf("q_ok:%d", item.ID)),
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("❌ %d", i+1), fmt.Sprintf("q_no:%d", item.ID)),
+		))
+	}
+	if total > len(items) {
+		b.WriteString(fmt.Sprintf("... va yana %d ta\n\n", total-len(items)))
+	}
+	b.WriteString(fmt.Sprintf("Jami: <b>%d</b> ta. ✅ - bazaga yozish, ❌ - rad etish", total))
+
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("✅ Hammasini tasdiqlash", fmt.Sprintf("q_okall:%d", pharmacyID)),
+		tgbotapi.NewInlineKeyboardButtonData("❌ Hammasini rad etish", fmt.Sprintf("q_noall:%d", pharmacyID)),
+	))
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(rows...)
+	return b.String(), &keyboard, nil
+}
+
+// statusEditInterval - Telegram bitta chatdagi xabarni tez-tez tahrirlashni cheklaydi
+const statusEditInterval = 2 * time.Second
+
+// importStatus - bitta xabarni tahrirlab import bosqichlarini ko'rsatish (HTML)
+type importStatus struct {
+	bot       *tgbotapi.BotAPI
+	chatID    int64
+	messageID int
+	text      string
+	lastEdit  time.Time
+}
+
+// newImportStatus - holat xabarini yuborish
+func newImportStatus(bot *tgbotapi.BotAPI, chatID int64, text string) *importStatus {
+	s := &importStatus{bot: bot, chatID: chatID, text: text, lastEdit: time.Now()}
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ParseMode = "HTML"
+	if sent, err := bot.Send(msg); err == nil {
+		s.messageID = sent.MessageID
+	}
+	return s
+}
+
+// Update - oraliq holat; oldingi tahrirdan beri statusEditInterval o'tmagan bo'lsa o'tkazib yuboriladi
+func (s *importStatus) Update(text string) {
+	if text == s.text || time.Since(s.lastEdit) < statusEditInterval {
+		return
+	}
+	s.edit(text, nil)
+}
+
+// Finish - yakuniy holat, har doim ko'rsatiladi (kerak bo'lsa tugmalar bilan)
+func (s *importStatus) Finish(text string, keyboard *tgbotapi.InlineKeyboardMarkup) {
+	if wait := time.Second - time.Since(s.lastEdit); wait > 0 {
+		time.Sleep(wait)
+	}
+	s.edit(text, keyboard)
+}
+
+func (s *importStatus) edit(text string, keyboard *tgbotapi.InlineKeyboardMarkup) {
+	s.text = text
+	s.lastEdit = time.Now()
+
+	// Xabar yuborilmagan bo'lsa yangisi yuboriladi
+	if s.messageID == 0 {
+		msg := tgbotapi.NewMessage(s.chatID, text)
+		msg.ParseMode = "HTML"
+		if keyboard != nil {
+			msg.ReplyMarkup = keyboard
+		}
+		if sent, err := s.bot.Send(msg); err == nil {
+			s.messageID = sent.MessageID
+		}
+		return
+	}
+
+	edit := tgbotapi.NewEditMessageText(s.chatID, s.messageID, text)
+	edit.ParseMode = "HTML"
+	edit.ReplyMarkup = keyboard
+	if _, err := s.bot.Send(edit); err != nil {
+		fmt.Printf("⚠️ Holat xabari yangilanmadi: %v\n", err)
+	}
+}
+
+// progressReader - yuklab olinayotgan baytlarni sanash
+type progressReader struct {
+	r      io.Reader
+	read   int64
+	onRead func(read int64)
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	p.read += int64(n)
+	p.onRead(p.read)
+	return n, err
+}
+
+// importModeName - import rejimi nomi
+func importModeName(mode string) string {
+	if mode == excel.ModeReplace {
+		return "to'liq sinxron"
+	}
+	return "qo'shish/yangilash"
+}
+
+// alertLocation - ertalabki ogohlantirishlar Toshkent vaqti bilan (UTC+5)
+var alertLocation = time.FixedZone("UZT", 5*60*60)
+
+// expiryAlertSentKey - oxirgi ogohlantirish sanasi (pharmacy_id = 0), qayta ishga tushganda takrorlanmaydi
+const expiryAlertSentKey = "expiry_alert_sent"
+
+// expiryAlertListSize - xabarda ko'rsatiladigan partiyalar soni (to'liq ro'yxat .xlsx da)
+const expiryAlertListSize = 15
+
+// loadExpiringBatches - dorixonaning o'z oynalari bo'yicha muddati yaqin partiyalari
+func loadExpiringBatches(db *sql.DB, pharmacyID int) ([]excel.ExpiringBatch, []int, error) {
+	windows := excel.LoadExpiryWindows(db, pharmacyID)
+	batches, err := excel.ExpiringBatches(db, pharmacyID, windows[len(windows)-1])
+	return batches, windows, err
+}
+
+// expiryBucketCounts - har bir oynadagi partiyalar soni (-1 - muddati o'tgan)
+func expiryBucketCounts(batches []excel.ExpiringBatch, windows []int) map[int]int {
+	counts := make(map[int]int)
+	for _, b := range batches {
+		counts[excel.ExpiryBucket(b.DaysLeft, windows)]++
+	}
+	return counts
+}
+
+// formatExpiryAlert - bitta dorixona uchun muddatlar xabari
+func formatExpiryAlert(pharmacyName string, batches []excel.ExpiringBatch, windows []int) string {
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("⏰ <b>%s: yaroqlilik muddatlari</b>\n\n", html.EscapeString(pharmacyName)))
+
+	counts := expiryBucketCounts(batches, windows)
+	b.WriteString(fmt.Sprintf("❌ Muddati o'tgan: <b>%d</b> ta partiya\n", counts[-1]))
+	for _, w := range windows {
+		b.WriteString(fmt.Sprintf("⚠️ %d kungacha: <b>%d</b> ta partiya\n", w, counts[w]))
+	}
+
+	if len(batches) > 0 {
+		b.WriteString("\n")
+	}
+	for i, batch := range batches {
+		if i == expiryAlertListSize {
+			b.WriteString(fmt.Sprintf("... va yana %d ta (faylda)\n", len(batches)-expiryAlertListSize))
+			break
+		}
+		icon := "⚠️"
+		if batch.DaysLeft < 0 {
+			icon = "❌"
+		}
+		series := ""
+		if batch.Series != "" {
+			series = " (" + html.EscapeString(batch.Series) + ")"
+		}
+		b.WriteString(fmt.Sprintf("%s %s%s - %s, %d dona\n",
+			icon, html.EscapeString(batch.Name), series, batch.Expiry.Format("02.01.2006"), batch.Count))
+	}
+	return b.String()
+}
+
+// sendExpiryReport - xabar va .xlsx faylni yuborish
+func sendExpiryReport(bot *tgbotapi.BotAPI, chatID int64, text string, batches []excel.ExpiringBatch,
+	windows map[int][]int, names map[int]string, fileName string) {
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ParseMode = "HTML"
+	bot.Send(msg)
+
+	if len(batches) == 0 {
+		return
+	}
+	report, err := excel.ExpiryReport(batches, windows, names)
+	if err != nil {
+		fmt.Println("error:", err)
+		return
+	}
+	bot.Send(tgbotapi.NewDocument(chatID, tgbotapi.FileBytes{Name: fileName, Bytes: report}))
+}
+
+// pharmacyDisplayName - sozlangan nom yoki "Dorixona N"
+func pharmacyDisplayName(names map[int]string, pharmacyID int) string {
+	if name := names[pharmacyID]; name != "" {
+		return name
+	}
+	return fmt.Sprintf("Dorixona %d", pharmacyID)
+}
+
+// collectExpiry - har bir dorixonaning muddati yaqin partiyalari va oynalari
+func collectExpiry(db *sql.DB) (map[int][]excel.ExpiringBatch, map[int][]int) {
+	batches := make(map[int][]excel.ExpiringBatch)
+	windows := make(map[int][]int)
+	for pharmacyID := 1; pharmacyID <= 3; pharmacyID++ {
+		list, w, err := loadExpiringBatches(db, pharmacyID)
+		if err != nil {
+			fmt.Printf("⚠️ Dorixona %d muddatlari o'qilmadi: %v\n", pharmacyID, err)
+			continue
+		}
+		batches[pharmacyID] = list
+		windows[pharmacyID] = w
+	}
+	return batches, windows
+}
+
+// sendExpiryDigest - barcha dorixonalar bo'yicha umumiy hisobot (super admin uchun)
+func sendExpiryDigest(bot *tgbotapi.BotAPI, chatID int64, names map[int]string,
+	batches map[int][]excel.ExpiringBatch, windows map[int][]int) {
+	var b strings.Builder
+	b.WriteString("📋 <b>Yaroqlilik muddatlari - umumiy hisobot</b>\n\n")
+
+	var all []excel.ExpiringBatch
+	for pharmacyID := 1; pharmacyID <= 3; pharmacyID++ {
+		w, ok := windows[pharmacyID]
+		if !ok {
+			continue
+		}
+		all = append(all, batches[pharmacyID]...)
+
+		counts := expiryBucketCounts(batches[pharmacyID], w)
+		b.WriteString(fmt.Sprintf("🏪 <b>%s</b>: ❌ %d", html.EscapeString(pharmacyDisplayName(names, pharmacyID)), counts[-1]))
+		for _, days := range w {
+			b.WriteString(fmt.Sprintf(" · %dk: %d", days, counts[days]))
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString(fmt.Sprintf("\nJami: <b>%d</b> ta partiya", len(all)))
+
+	date := time.Now().In(alertLocation).Format("2006-01-02")
+	sendExpiryReport(bot, chatID, b.String(), all, windows, names, fmt.Sprintf("muddatlar_%s.xlsx", date))
+}
+
+// sendExpiryAlerts - har bir dorixona adminiga o'z ro'yxati, super adminga umumiy hisobot
+func sendExpiryAlerts(bot *tgbotapi.BotAPI, db *sql.DB) {
+	names := getAllPharmacies(db)
+	batches, windows := collectExpiry(db)
+	date := time.Now().In(alertLocation).Format("2006-01-02")
+
+	for adminID, pharmacyID := range adminPharmacy {
+		// Ogohlantiradigan narsa bo'lmasa dorixona adminiga yozilmaydi
+		if adminID == 0 || len(batches[pharmacyID]) == 0 {
+			continue
+		}
+		sendExpiryReport(bot, adminID,
+			formatExpiryAlert(pharmacyDisplayName(names, pharmacyID), batches[pharmacyID], windows[pharmacyID]),
+			batches[pharmacyID], windows, names,
+			fmt.Sprintf("muddatlar_%d_%s.xlsx", pharmacyID, date))
+	}
+
+	if superAdminID != 0 {
+		sendExpiryDigest(bot, superAdminID, names, batches, windows)
+	}
+}
+
+// runExpiryScheduler - har kuni ertalab soat alertHour da muddatlar haqida ogohlantirish.
+// Bot shu vaqtdan keyin ishga tushsa, o'sha kuni yuborilmagan bo'lsa darhol yuboriladi.
+func runExpiryScheduler(bot *tgbotapi.BotAPI, db *sql.DB, alertHour int) {
+	ticker := time.NewTicker(10 * time.Minute)
+	defer ticker.Stop()
+
+	for {
+		now := time.Now().In(alertLocation)
+		today := now.Format("2006-01-02")
+		if now.Hour() >= alertHour && getSetting(db, expiryAlertSentKey, 0) != today {
+			// Avval sana yoziladi - xato bo'lsa ham kuniga bir marta
+			if err := updateSetting(db, expiryAlertSentKey, today, 0); err != nil {
+				fmt.Printf("⚠️ Muddat ogohlantirishi sanasi yozilmadi: %v\n", err)
+			} else {
+				fmt.Println("⏰ Yaroqlilik muddatlari haqida ogohlantirish yuborilmoqda...")
+				sendExpiryAlerts(bot, db)
+			}
+		}
+		<-ticker.C
+	}
+}
+
+// apiKeySettingKey - settings jadvalida dorixona API kalitining SHA-256 xeshi
+const apiKeySettingKey = "api_key_hash"
+
+// maxAPIUploadSize - API orqali yuklanadigan fayl chegarasi
+const maxAPIUploadSize = 50 << 20
+
+// apiImportResult - POST /api/pharmacies/{id}/imports javobi
+type apiImportResult struct {
+	ImportID    int              `json:"import_id"`
+	PharmacyID  int              `json:"pharmacy_id"`
+	FileName    string           `json:"file_name"`
+	Mode        string           `json:"mode"`
+	Saved       int              `json:"saved"`
+	Inserted    int              `json:"inserted"`
+	Updated     int              `json:"updated"`
+	Removed     int              `json:"removed"`
+	Duplicates  int              `json:"duplicates"`
+	Skipped     int              `json:"skipped"`
+	Quarantined int              `json:"quarantined"`
+	DurationMS  int64            `json:"duration_ms"`
+	Errors      []excel.RowIssue `json:"errors"`
+}
+
+// hashAPIKey - kalitning o'zi bazada saqlanmaydi
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// generateAPIKey - yangi tasodifiy kalit
+func generateAPIKey() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return "pk_" + hex.EncodeToString(buf), nil
+}
+
+// checkAPIKey - so'rovdagi kalit dorixonaniki ekanligini tekshirish
+func checkAPIKey(db *sql.DB, pharmacyID int, r *http.Request) bool {
+	key := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if key == "" {
+		key = r.Header.Get("X-API-Key")
+	}
+	stored := getSetting(db, apiKeySettingKey, pharmacyID)
+	if key == "" || stored == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(hashAPIKey(key)), []byte(stored)) == 1
+}
+
+// pharmacyAdminChat - dorixona admini chat ID (natija xabari uchun), topilmasa 0
+func pharmacyAdminChat(pharmacyID int) int64 {
+	for adminID, pid := range adminPharmacy {
+		if adminID != 0 && pid == pharmacyID {
+			return adminID
+		}
+	}
+	return 0
+}
+
+// stageFromReader - faylni formatiga qarab parse qilish (bazaga yozilmaydi).
+// mode bo'sh bo'lsa dorixonaning standart rejimi; birorta dori o'qilmasa xato.
+func stageFromReader(db *sql.DB, pharmacyID int, fileName string, r io.Reader, mode string) (*excel.StagedImport, error) {
+	phone := getSetting(db, "phone", pharmacyID)
+	address := getSetting(db, "address", pharmacyID)
+	if phone == "" || address == "" {
+		return nil, fmt.Errorf("dorixona %d telefoni yoki manzili kiritilmagan", pharmacyID)
+	}
+
+	// .xml/.zip nomining o'zi yetmaydi - .zip oddiy .xlsx bo'lishi ham mumkin
+	isCommerceML := false
+	var err error
+	if excel.IsCommerceMLFile(fileName) {
+		if r, isCommerceML, err = excel.SniffCommerceML(r); err != nil {
+			return nil, err
+		}
+	}
+
+	var staged *excel.StagedImport
+	if excel.IsCSVFile(fileName) {
+		staged, err = excel.PrepareCSV(db, fileName, r, phone, address, pharmacyID)
+	} else if isCommerceML {
+		staged, err = excel.PrepareCommerceML(db, fileName, r, phone, address, pharmacyID)
+	} else if strings.EqualFold(filepath.Ext(fileName), ".xml") {
+		return nil, fmt.Errorf("XML fayl 1C CommerceML emas (КоммерческаяИнформация elementi yo'q)")
+	} else {
+		staged, err = excel.PrepareExcel(db, fileName, r, phone, address, pharmacyID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if mode != "" {
+		staged.Mode = mode
+	}
+	if len(staged.Medicines) == 0 {
+		return nil, emptyImportError(staged)
+	}
+	return staged, nil
+}
+
+// emptyImportError - faylda birorta ham dori o'qilmadi; birinchi o'tkazilgan qatorlar sababi bilan
+func emptyImportError(staged *excel.StagedImport) error {
+	msg := "faylda dori topilmadi"
+	var reasons []string
+	for _, issue := range staged.Issues {
+		if issue.Kind != excel.IssueSkipped {
+			continue
+		}
+		if len(reasons) == 3 {
+			break
+		}
+		reasons = append(reasons, fmt.Sprintf("%d-qator: %s", issue.Row, issue.Reason))
+	}
+	if len(reasons) > 0 {
+		msg += fmt.Sprintf(" (o'tkazilgan qatorlar: %d; %s)", staged.Skipped, strings.Join(reasons, "; "))
+	}
+	return fmt.Errorf("%s", msg)
+}
+
+// commitAndNotify - tasdiqsiz saqlash, natija dorixona adminiga yuboriladi
+func commitAndNotify(db *sql.DB, bot *tgbotapi.BotAPI, staged *excel.StagedImport) (*excel.ImportResult, error) {
+	res, err := staged.Commit(db, nil)
+	if err != nil {
+		return nil, err
+	}
+	if chatID := pharmacyAdminChat(staged.PharmacyID); chatID != 0 {
+		sendImportResult(bot, chatID, res)
+	}
+	return res, nil
+}
+
+// importFromReader - faylni tasdiqsiz import qilish (papka va boshqa avtomatik manbalar uchun)
+func importFromReader(db *sql.DB, bot *tgbotapi.BotAPI, pharmacyID int, fileName string, r io.Reader, mode string) (*excel.ImportResult, error) {
+	staged, err := stageFromReader(db, pharmacyID, fileName, r, mode)
+	if err != nil {
+		return nil, err
+	}
+	return commitAndNotify(db, bot, staged)
+}
+
+// writeJSON - JSON javob
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeJSONError - {"error": "..."} javob
+func writeJSONError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
+
+// handleAPIImport - POST /api/pharmacies/{id}/imports (multipart: file, ixtiyoriy mode)
+func handleAPIImport(db *sql.DB, bot *tgbotapi.BotAPI) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		pharmacyID, err := strconv.Atoi(r.PathValue("id"))
+		if err != nil || pharmacyID < 1 || pharmacyID > 3 {
+			writeJSONError(w, http.StatusNotFound, "dorixona topilmadi")
+			return
+		}
+		if !checkAPIKey(db, pharmacyID, r) {
+			writeJSONError(w, http.StatusUnauthorized, "API kalit noto'g'ri")
+			return
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, maxAPIUploadSize)
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, "multipart 'file' maydoni kerak: "+err.Error())
+			return
+		}
+		defer file.Close()
+
+		mode := r.FormValue("mode")
+		if mode != "" && mode != excel.ModeMerge && mode != excel.ModeReplace {
+			writeJSONError(w, http.StatusBadRequest, "mode merge yoki replace bo'lishi kerak")
+			return
+		}
+
+		staged, err := stageFromReader(db, pharmacyID, header.Filename, file, mode)
+		if err != nil {
+			writeJSONError(w, http.StatusUnprocessableEntity, err.Error())
+			return
+		}
+		res, err := commitAndNotify(db, bot, staged)
+		if err != nil {
+			fmt.Println("error:", err)
+			writeJSONError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		fmt.Printf("🌐 API import: dorixona %d, %s, %d ta saqlandi\n", pharmacyID, header.Filename, res.Saved)
+		writeJSON(w, http.StatusCreated, apiImportResult{
+			ImportID:    res.ImportID,
+			PharmacyID:  pharmacyID,
+			FileName:    res.FileName,
+			Mode:        res.Mode,
+			Saved:       res.Saved,
+			Inserted:    res.Inserted,
+			Updated:     res.Updated,
+			Removed:     res.Removed,
+			Duplicates:  res.Duplicates,
+			Skipped:     res.Skipped,
+			Quarantined: len(res.Quarantined),
+			DurationMS:  res.Duration.Milliseconds(),
+			Errors:      append([]excel.RowIssue{}, res.Issues...),
+		})
+	}
+}
+
+// inboxHandler - papkaga tushgan faylni import qilish, xato bo'lsa dorixona adminiga xabar
+func inboxHandler(bot *tgbotapi.BotAPI, db *sql.DB) inbox.Handler {
+	return func(pharmacyID int, path string) error {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		// Muvaffaqiyatli natija importFromReader ichida adminga yuboriladi
+		_, err = importFromReader(db, bot, pharmacyID, filepath.Base(path), f, "")
+		if err != nil {
+			if chatID := pharmacyAdminChat(pharmacyID); chatID != 0 {
+				msg := tgbotapi.NewMessage(chatID, fmt.Sprintf(
+					"❌ <b>Papkadagi fayl import qilinmadi</b>\n\n📁 Fayl: <code>%s</code>\n⚠️ %s\n\nFayl <code>%s/</code> papkasiga ko'chirildi",
+					html.EscapeString(filepath.Base(path)), html.EscapeString(err.Error()), inbox.FailedDir))
+				msg.ParseMode = "HTML"
+				bot.Send(msg)
+			}
+			return err
+		}
+		return nil
+	}
+}
+
+// wipePharmacy - dorixonaning dorilari, partiyalari va karantini bitta transactionda o'chiriladi
+func wipePharmacy(db *sql.DB, pharmacyID int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, table := range []string{"medicine_batches", "import_quarantine", "medicines"} {
+		if _, err := tx.Exec("DELETE FROM "+table+" WHERE pharmacy_id = $1", pharmacyID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// getAllPharmacies - barcha dorixonalar ro'yxati
+func getAllPharmacies(db *sql.DB) map[int]string {
+	pharmacies := make(map[int]string)
+	
+	rows, err := db.Query(`
+		SELECT DISTINCT pharmacy_id, value 
+		FROM settings 
+		WHERE key = 'name' 
+		ORDER BY pharmacy_id
+	`)
+	if err != nil {
+		return pharmacies
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int
+		var name string
+		if err := rows.Scan(&id, &name); err == nil {
+			pharmacies[id] = name
+		}
+	}
+
+	return pharmacies
+}
+
+func main() {
+	err := godotenv.Load()
+	if err != nil {
+		log.Fatal(".env topilmadi")
+	}
+
+	botToken := os.Getenv("BOT_TOKEN")
+	
+	// Super admin va oddiy adminlarni o'qish
+	superAdminID, _ = strconv.ParseInt(os.Getenv("ADMIN_ID_1"), 10, 64)
+	admin2, _ := strconv.ParseInt(os.Getenv("ADMIN_ID_2"), 10, 64)
+	admin3, _ := strconv.ParseInt(os.Getenv("ADMIN_ID_3"), 10, 64)
+	admin4, _ := strconv.ParseInt(os.Getenv("ADMIN_ID_4"), 10, 64)
+	
+	adminIDs = []int64{admin2, admin3, admin4}
+	
+	// Har bir adminga dorixona biriktirish
+	adminPharmacy[admin2] = 1 // Dorixona 1
+	adminPharmacy[admin3] = 2 // Dorixona 2
+	adminPharmacy[admin4] = 3 // Dorixona 3
+
+	bot, err := tgbotapi.NewBotAPI(botToken)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	db := connectDB()
+	defer db.Close()
+
+	// Healthcheck endpoint (Railway uchun)
+	go func() {
+		http.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte("OK"))
+		})
+		
+		http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte("Pharmacy Bot is running"))
+		})
+
+		// Back-office uchun import API (dorixona API kaliti bilan)
+		http.HandleFunc("POST /api/pharmacies/{id}/imports", handleAPIImport(db, bot))
+		
+		port := os.Getenv("PORT")
+		if port == "" {
+			port = "8080"
+		}
+		
+		fmt.Printf("🌐 Health check server running on :%s\n", port)
+		if err := http.ListenAndServe(":"+port, nil); err != nil {
+			log.Printf("⚠️ Health check server error: %v\n", err)
+		}
+	}()
+
+	// Yaroqlilik muddatlari - har kuni ertalab ogohlantirish
+	alertHour := 9
+	if h, err := strconv.Atoi(os.Getenv("EXPIRY_ALERT_HOUR")); err == nil && h >= 0 && h < 24 {
+		alertHour = h
+	}
+	go runExpiryScheduler(bot, db, alertHour)
+
+	// Inbox papkasi - kassa dasturlari tashlagan fayllarni avtomatik import qilish (ixtiyoriy)
+	if inboxDir := os.Getenv("INBOX_DIR"); inboxDir != "" {
+		watcher, err := inbox.New(inboxDir, []int{1, 2, 3}, inboxHandler(bot, db))
+		if err != nil {
+			log.Printf("⚠️ Inbox ishga tushmadi: %v\n", err)
+		} else {
+			go func() {
+				fmt.Printf("📂 Inbox kuzatilmoqda: %s\n", inboxDir)
+				if err := watcher.Run(); err != nil {
+					log.Printf("⚠️ Inbox to'xtadi: %v\n", err)
+				}
+			}()
+		}
+	}
+
+	// Graceful shutdown
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+	
+	go func() {
+		<-c
+		fmt.Println("\n🛑 Bot to'xtatilmoqda...")
+		bot.StopReceivingUpdates()
+		db.Close()
+		fmt.Println("✅ Bot to'xtatildi")
+		os.Exit(0)
+	}()
+
+	u := tgbotapi.NewUpdate(0)
+	u.Timeout = 60
+	updates := bot.GetUpdatesChan(u)
+
+	fmt.Println("✅ Bot ishga tushdi...")
+	fmt.Printf("🤖 Bot username: @%s\n", bot.Self.UserName)
+	fmt.Printf("👑 Super Admin: %d\n", superAdminID)
+	fmt.Printf("👥 Dorixona Adminlari:\n")
+	for _, aid := range adminIDs {
+		fmt.Printf("   - %d (Dorixona %d)\n", aid, adminPharmacy[aid])
+	}
+
+	for update := range updates {
+		// Inline tugmalar - ko'p sheetli workbook sheetlarini dorixonalarga moslash
+		if update.CallbackQuery != nil && strings.HasPrefix(update.CallbackQuery.Data, "wb_") {
+			cq := update.CallbackQuery
+			parts := strings.Split(cq.Data, ":")
+			if len(parts) < 2 {
+				continue
+			}
+
+			wb, ok := pendingWorkbooks[parts[1]]
+			if !ok || wb.userID != cq.From.ID || cq.Message == nil {
+				bot.Request(tgbotapi.NewCallback(cq.ID, "Workbook topilmadi yoki muddati o'tgan"))
+				continue
+			}
+			wbID := parts[1]
+
+			switch parts[0] {
+			case "wb_map":
+				if len(parts) != 4 {
+					continue
+				}
+				if len(wb.staged) > 0 {
+					bot.Request(tgbotapi.NewCallback(cq.ID, "Preview tayyor - moslashni o'zgartirish uchun bekor qilib qayta yuboring"))
+					continue
+				}
+				sheetIdx, _ := strconv.Atoi(parts[2])
+				pharmacyID, _ := strconv.Atoi(parts[3])
+				if sheetIdx < 0 || sheetIdx >= len(wb.sheets) {
+					continue
+				}
+				wb.mapping[wb.sheets[sheetIdx]] = pharmacyID
+
+				text, keyboard := workbookMappingMessage(db, wbID, wb)
+				edit := tgbotapi.NewEditMessageTextAndMarkup(cq.Message.Chat.ID, cq.Message.MessageID, text, keyboard)
+				edit.ParseMode = "HTML"
+				bot.Send(edit)
+				bot.Request(tgbotapi.NewCallback(cq.ID, ""))
+
+			case "wb_cancel":
+				delete(pendingWorkbooks, wbID)
+				for _, st := range wb.staged {
+					delete(pendingImports, st.importID)
+				}
+				bot.Send(tgbotapi.NewEditMessageText(cq.Message.Chat.ID, cq.Message.MessageID, cq.Message.Text+"\n\n❌ Bekor qilindi"))
+				bot.Request(tgbotapi.NewCallback(cq.ID, "Bekor qilindi"))
+
+			case "wb_import":
+				if len(wb.staged) > 0 {
+					bot.Request(tgbotapi.NewCallback(cq.ID, "Preview allaqachon tayyor"))
+					continue
+				}
+				if time.Since(wb.created) > excel.StagedImportTTL {
+					delete(pendingWorkbooks, wbID)
+					bot.Send(tgbotapi.NewEditMessageText(cq.Message.Chat.ID, cq.Message.MessageID, cq.Message.Text+"\n\n⌛ Muddati o'tdi, faylni qaytadan yuboring"))
+					bot.Request(tgbotapi.NewCallback(cq.ID, "Muddati o'tdi"))
+					continue
+				}
+				bot.Request(tgbotapi.NewCallback(cq.ID, "Tekshirilmoqda..."))
+
+				status := &importStatus{bot: bot, chatID: cq.Message.Chat.ID, messageID: cq.Message.MessageID}
+				status.Finish("🔎 Sheetlar o'qilmoqda va bazadagi bilan solishtirilmoqda...", nil)
+				text := stageWorkbook(db, bot, wb, cq.Message.Chat.ID)
+				wb.data = nil // sheetlar parse qilindi, fayl endi kerak emas
+				if len(wb.staged) == 0 {
+					delete(pendingWorkbooks, wbID)
+					status.Finish(text+"\n\n❌ Yuklanadigan sheet yo'q", nil)
+					continue
+				}
+
+				keyboard := tgbotapi.NewInlineKeyboardMarkup(
+					tgbotapi.NewInlineKeyboardRow(
+						tgbotapi.NewInlineKeyboardButtonData("✅ Qo'shish/yangilash", "wb_merge:"+wbID),
+						tgbotapi.NewInlineKeyboardButtonData("🔁 To'liq sinxron", "wb_replace:"+wbID),
+					),
+					tgbotapi.NewInlineKeyboardRow(
+						tgbotapi.NewInlineKeyboardButtonData("❌ Bekor qilish", "wb_cancel:"+wbID),
+					),
+				)
+				status.Finish(text, &keyboard)
+
+			case "wb_merge", "wb_replace":
+				if len(wb.staged) == 0 {
+					continue
+				}
+				delete(pendingWorkbooks, wbID)
+				bot.Request(tgbotapi.NewCallback(cq.ID, "Saqlanmoqda..."))
+
+				mode := excel.ModeMerge
+				if parts[0] == "wb_replace" {
+					mode = excel.ModeReplace
+				}
+				status := &importStatus{bot: bot, chatID: cq.Message.Chat.ID, messageID: cq.Message.MessageID}
+				status.Finish("💾 Saqlanmoqda...", nil)
+				status.Finish(commitWorkbook(db, bot, wb, mode, status), nil)
+			}
+			continue
+		}
+
+		// Inline tugmalar - karantindagi dorini tasdiqlash yoki rad etish
+		if update.CallbackQuery != nil && strings.HasPrefix(update.CallbackQuery.Data, "q_") {
+			cq := update.CallbackQuery
+			action, arg, _ := strings.Cut(cq.Data, ":")
+			id, _ := strconv.Atoi(arg)
+
+			// Har bir tugma uchun dorixona aniqlanadi - admin faqat o'z dorixonasini boshqaradi
+			pharmacyID := id
+			if action == "q_ok" || action == "q_no" {
+				item, err := excel.GetQuarantine(db, id)
+				if err != nil {
+					bot.Request(tgbotapi.NewCallback(cq.ID, "Yozuv topilmadi yoki allaqachon ko'rib chiqilgan"))
+					continue
+				}
+				pharmacyID = item.PharmacyID
+			}
+			if !canManagePharmacy(cq.From.ID, pharmacyID) {
+				bot.Request(tgbotapi.NewCallback(cq.ID, "❌ Siz bu dorixona admini emassiz"))
+				continue
+			}
+
+			var status string
+			var err error
+			switch action {
+			case "q_ok":
+				var item *excel.QuarantineItem
+				if item, err = excel.ApproveQuarantine(db, id); err == nil {
+					status = "✅ Saqlandi: " + item.Medicine.Name
+				}
+			case "q_no":
+				var item *excel.QuarantineItem
+				if item, err = excel.RejectQuarantine(db, id); err == nil {
+					status = "❌ Rad etildi: " + item.Medicine.Name
+				}
+			case "q_okall", "q_noall":
+				var n, stale int
+				n, stale, err = excel.ResolveAllQuarantine(db, pharmacyID, action == "q_okall")
+				if action == "q_okall" {
+					status = fmt.Sprintf("✅ Saqlandi: %d ta", n)
+					if stale > 0 {
+						status += fmt.Sprintf(", %d ta o'zgargani uchun qoldirildi", stale)
+					}
+				} else {
+					status = fmt.Sprintf("❌ Rad etildi: %d ta", n)
+				}
+			default:
+				continue
+			}
+			if err != nil {
+				fmt.Println("error:", err)
+				status = "❌ Xato: " + err.Error()
+			}
+			bot.Request(tgbotapi.NewCallback(cq.ID, status))
+
+			// Ro'yxat yangilanadi
+			if cq.Message != nil {
+				text, keyboard, err := quarantineMessage(db, pharmacyID)
+				if err != nil {
+					continue
+				}
+				edit := tgbotapi.NewEditMessageText(cq.Message.Chat.ID, cq.Message.MessageID, text)
+				edit.ParseMode = "HTML"
+				edit.ReplyMarkup = keyboard
+				bot.Send(edit)
+			}
+			continue
+		}
+
+		// Inline tugmalar - importni tasdiqlash yoki bekor qilish
+		if update.CallbackQuery != nil {
+			cq := update.CallbackQuery
+			action, importID, _ := strings.Cut(cq.Data, ":")
+			if action != "import_merge" && action != "import_replace" && action != "import_cancel" {
+				continue // noma'lum tugma
+			}
+
+			p, ok := pendingImports[importID]
+			if !ok || p.userID != cq.From.ID {
+				bot.Request(tgbotapi.NewCallback(cq.ID, "Import topilmadi yoki muddati o'tgan"))
+				continue
+			}
+			delete(pendingImports, importID)
+
+			// Preview xabari tahrirlanib saqlash jarayoni ko'rsatiladi
+			base := ""
+			var progress *importStatus
+			if cq.Message != nil {
+				base = html.EscapeString(cq.Message.Text) + "\n\n"
+				progress = &importStatus{bot: bot, chatID: cq.Message.Chat.ID, messageID: cq.Message.MessageID}
+			}
+
+			var status string
+			answered := false
+			switch {
+			case action == "import_cancel":
+				status = "❌ Import bekor qilindi"
+			case p.staged.Expired():
+				status = "⌛ Import muddati o'tdi, faylni qaytadan yuboring"
+			default:
+				p.staged.Mode = excel.ModeMerge
+				if action == "import_replace" {
+					p.staged.Mode = excel.ModeReplace
+				}
+
+				// Callback darhol javob oladi - saqlash uzoq davom etishi mumkin
+				bot.Request(tgbotapi.NewCallback(cq.ID, "Saqlanmoqda..."))
+				answered = true
+				var reporter excel.Reporter
+				if progress != nil {
+					progress.Finish(base+"💾 Saqlanmoqda...", nil)
+					reporter = excel.ProgressFunc(func(saved, total int) {
+						progress.Update(fmt.Sprintf("%s💾 Saqlanmoqda: %d/%d", base, saved, total))
+					})
+				}
+
+				res, err := p.staged.Commit(db, reporter)
+				if err != nil {
+					fmt.Println("error:", err)
+					status = "❌ DB ga yozishda xato: " + err.Error()
+				} else {
+					status = fmt.Sprintf("✅ Import tasdiqlandi: %d ta saqlandi", res.Saved)
+					sendImportResult(bot, p.chatID, res)
+				}
+			}
+
+			if !answered {
+				bot.Request(tgbotapi.NewCallback(cq.ID, status))
+			}
+			if progress != nil {
+				progress.Finish(base+html.EscapeString(status), nil)
+			}
+			continue
+		}
+
+		if update.Message == nil {
+			continue
+		}
+
+		userID := update.Message.From.ID
+
+		// /start komandasi
+		if update.Message.Text == "/start" {
+			// Barcha dorixonalarning ma'lumotlarini ko'rsatish
+			var welcomeMsg strings.Builder
+			welcomeMsg.WriteString("🏥 <b>Dorixonalar tarmog'iga xush kelibsiz!</b>\n\n")
+			welcomeMsg.WriteString("💊 <b>Dori qidirish:</b>\n")
+			welcomeMsg.WriteString("Qidirmoqchi bo'lgan doringiz nomini yozing\n\n")
+			
+			pharmacies := getAllPharmacies(db)
+			if len(pharmacies) > 0 {
+				welcomeMsg.WriteString("📍 <b>Bizning filiallar:</b>\n\n")
+				for id := 1; id <= 3; id++ {
+					name := getSetting(db, "name", id)
+					phone := getSetting(db, "phone", id)
+					address := getSetting(db, "address", id)
+					
+					if name == "" {
+						name = fmt.Sprintf("Dorixona %d", id)
+					}
+					
+					welcomeMsg.WriteString(fmt.Sprintf("🏪 <b>%s</b>\n", name))
+					if phone != "" {
+						welcomeMsg.WriteString(fmt.Sprintf("📞 %s\n", phone))
+					}
+					if address != "" {
+						welcomeMsg.WriteString(fmt.Sprintf("📍 %s\n", address))
+					}
+					welcomeMsg.WriteString("\n")
+				}
+			}
+			
+			welcomeMsg.WriteString("✍️ Dori nomini yozing va qidiruvni boshlang!")
+			
+			msg := tgbotapi.NewMessage(update.Message.Chat.ID, welcomeMsg.String())
+			msg.ParseMode = "HTML"
+			bot.Send(msg)
+			continue
+		}
+
+		// /help komandasi
+		if update.Message.Text == "/help" {
+			helpMsg := 
+				"📖 <b>Yordam</b>\n\n" +
+				"🔍 <b>Qidirish:</b>\n" +
+				"Dori nomini rus yoki lotin harflarida yozing\n" +
+				"Yoki qutidagi shtrix-kodni (8 yoki 13 raqam) yozing\n" +
+				"📷 Shtrix-kod rasmini yuborsangiz ham bo'ladi\n\n" +
+				"💡 <b>Maslahatlar:</b>\n" +
+				"• To'liq nom yozmasangiz ham bo'ladi\n" +
+				"• Katta-kichik harf farqi yo'q\n" +
+				"• Bir necha so'z bilan qidiring\n\n" +
+				"📋 Qidiruvda barcha filiallardan natija chiqadi"
+			
+			msg := tgbotapi.NewMessage(update.Message.Chat.ID, helpMsg)
+			msg.ParseMode = "HTML"
+			bot.Send(msg)
+			continue
+		}
+
+		// /ahad - MA'LUMOTLARNI O'CHIRISH
+		if update.Message.Text == "/ahad" {
+			if !isAdmin(userID) {
+				bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "❌ Siz admin emassiz"))
+				continue
+			}
+
+			var confirmMsg string
+			
+			if isSuperAdmin(userID) {
+				// Super admin - dorixona tanlash kerak
+				confirmMsg = 
+					"⚠️ <b>DIQQAT!</b>\n\n" +
+					"Qaysi dorixonaning ma'lumotlarini o'chirmoqchisiz?\n\n" +
+					"<code>/ahad 1</code> - Dorixona 1 ning barcha dorilarini o'chirish\n" +
+					"<code>/ahad 2</code> - Dorixona 2 ning barcha dorilarini o'chirish\n" +
+					"<code>/ahad 3</code> - Dorixona 3 ning barcha dorilarini o'chirish\n" +
+					"<code>/ahad all</code> - BARCHA dorixonalarning ma'lumotlarini o'chirish\n\n" +
+					"⚠️ Bu amaliyot QAYTARIB BO'LMAYDI!"
+			} else {
+				// Oddiy admin - faqat o'z dorixonasi
+				pharmacyID := getPharmacyID(userID)
+				pharmacyName := getSetting(db, "name", pharmacyID)
+				if pharmacyName == "" {
+					pharmacyName = fmt.Sprintf("Dorixona %d", pharmacyID)
+				}
+				
+				confirmMsg = fmt.Sprintf(
+					"⚠️ <b>DIQQAT!</b>\n\n"+
+					"Siz <b>%s</b> ning barcha dorilarini o'chirmoqchisiz\n\n"+
+					"🗑 Barcha dorilar o'chiriladi\n\n"+
+					"Bu amaliyotni <b>QAYTARIB BO'LMAYDI!</b>\n\n"+
+					"Davom etish uchun:\n"+
+					"<code>/ahad_confirm</code>\n\n"+
+					"Bekor qilish uchun:\n"+
+					"<code>/cancel</code>",
+					pharmacyName,
+				)
+			}
+			
+			msg := tgbotapi.NewMessage(update.Message.Chat.ID, confirmMsg)
+			msg.ParseMode = "HTML"
+			bot.Send(msg)
+			continue
+		}
+
+		// /ahad 1, /ahad 2, /ahad 3, /ahad all - Super admin uchun dorixona tanlash
+		if strings.HasPrefix(update.Message.Text, "/ahad ") {
+			if !isSuperAdmin(userID) {
+				bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "❌ Bu komanda faqat super admin uchun"))
+				continue
+			}
+
+			parts := strings.Split(strings.TrimSpace(update.Message.Text), " ")
+			if len(parts) != 2 {
+				bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "❌ Noto'g'ri format"))
+				continue
+			}
+
+			target := parts[1]
+			var confirmMsg string
+
+			if target == "all" {
+				// Barcha dorixonalarni o'chirish
+				uploadSession[userID] = -1 // -1 = barcha dorixonalar
+				
+				confirmMsg = 
+					"⚠️ <b>JUDA KATTA DIQQAT!</b>\n\n" +
+					"Siz <b>BARCHA 3 TA DORIXONANING</b> ma'lumotlarini o'chirmoqchisiz:\n\n" +
+					"🗑 Dorixona 1 - barcha dorilar\n" +
+					"🗑 Dorixona 2 - barcha dorilar\n" +
+					"🗑 Dorixona 3 - barcha dorilar\n\n" +
+					"Bu amaliyotni <b>QAYTARIB BO'LMAYDI!</b>\n\n" +
+					"Davom etish uchun:\n" +
+					"<code>/ahad_confirm</code>\n\n" +
+					"Bekor qilish uchun:\n" +
+					"<code>/cancel</code>"
+			} else {
+				// Bitta dorixonani o'chirish
+				pharmacyID, err := strconv.Atoi(target)
+				if err != nil || pharmacyID < 1 || pharmacyID > 3 {
+					bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "❌ Dorixona raqami 1, 2, 3 yoki 'all' bo'lishi kerak"))
+					continue
+				}
+
+				uploadSession[userID] = pharmacyID // Session'da saqlab qo'yish
+				
+				pharmacyName := getSetting(db, "name", pharmacyID)
+				if pharmacyName == "" {
+					pharmacyName = fmt.Sprintf("Dorixona %d", pharmacyID)
+				}
+
+				confirmMsg = fmt.Sprintf(
+					"⚠️ <b>DIQQAT!</b>\n\n"+
+					"Siz <b>%s</b> ning barcha dorilarini o'chirmoqchisiz\n\n"+
+					"🗑 Barcha dorilar o'chiriladi\n\n"+
+					"Bu amaliyotni <b>QAYTARIB BO'LMAYDI!</b>\n\n"+
+					"Davom etish uchun:\n"+
+					"<code>/ahad_confirm</code>\n\n"+
+					"Bekor qilish uchun:\n"+
+					"<code>/cancel</code>",
+					pharmacyName,
+				)
+			}
+
+			msg := tgbotapi.NewMessage(update.Message.Chat.ID, confirmMsg)
+			msg.ParseMode = "HTML"
+			bot.Send(msg)
+			continue
+		}
+
+		// /ahad_confirm - Tasdiqlangan o'chirish
+		if update.Message.Text == "/ahad_confirm" {
+			if !isAdmin(userID) {
+				bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "❌ Siz admin emassiz"))
+				continue
+			}
+
+			var pharmacyID int
+			var deleteAll bool
+
+			if isSuperAdmin(userID) {
+				// Super admin uchun session'dan olish
+				if pid, ok := uploadSession[userID]; ok {
+					if pid == -1 {
+						deleteAll = true
+					} else {
+						pharmacyID = pid
+					}
+					delete(uploadSession, userID) // Session tozalash
+				} else {
+					bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, 
+						"❌ Avval /ahad 1, /ahad 2, /ahad 3 yoki /ahad all ni tanlang"))
+					continue
+				}
+			} else {
+				// Oddiy admin - faqat o'z dorixonasi
+				pharmacyID = getPharmacyID(userID)
+			}
+
+			var medicinesCount int64
+			var successMsg string
+
+			if deleteAll {
+				// Barcha dorixonalarning dorilarini o'chirish
+				db.QueryRow("SELECT COUNT(*) FROM medicines").Scan(&medicinesCount)
+				
+				// Partiyalar va karantin ham - aks holda muddat ogohlantirishlarida o'chgan dorilar qoladi
+				_, err := db.Exec("TRUNCATE TABLE medicines, medicine_batches, import_quarantine RESTART IDENTITY")
+				if err != nil {
+					bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "❌ O'chirishda xato: "+err.Error()))
+					continue
+				}
+
+				successMsg = fmt.Sprintf(
+					"✅ <b>Barcha dorixonalar tozalandi!</b>\n\n"+
+					"🗑 O'chirilgan dorilar: <b>%d</b> ta\n\n"+
+					"📝 Endi qaytadan Excel yuklashingiz mumkin",
+					medicinesCount,
+				)
+			} else {
+				// Bitta dorixonaning dorilarini o'chirish
+				db.QueryRow("SELECT COUNT(*) FROM medicines WHERE pharmacy_id = $1", pharmacyID).Scan(&medicinesCount)
+				
+				err := wipePharmacy(db, pharmacyID)
+				if err != nil {
+					bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "❌ O'chirishda xato: "+err.Error()))
+					continue
+				}
+
+				pharmacyName := getSetting(db, "name", pharmacyID)
+				if pharmacyName == "" {
+					pharmacyName = fmt.Sprintf("Dorixona %d", pharmacyID)
+				}
+
+				successMsg = fmt.Sprintf(
+					"✅ <b>%s tozalandi!</b>\n\n"+
+					"🗑 O'chirilgan dorilar: <b>%d</b> ta\n\n"+
+					"📝 Endi qaytadan Excel yuklashingiz mumkin",
+					pharmacyName, medicinesCount,
+				)
+			}
+
+			msg := tgbotapi.NewMessage(update.Message.Chat.ID, successMsg)
+			msg.ParseMode = "HTML"
+			bot.Send(msg)
+			continue
+		}
+
+		// /cancel - Bekor qilish
+		if update.Message.Text == "/cancel" {
+			// Session tozalash
+			delete(uploadSession, userID)
+			delete(workbookSession, userID)
+			bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "✅ Bekor qilindi"))
+			continue
+		}
+
+		// /admin - admin panel
+		if update.Message.Text == "/admin" {
+			if !isAdmin(userID) {
+				bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "❌ Siz admin emassiz"))
+				continue
+			}
+
+			var adminMsg strings.Builder
+			adminMsg.WriteString("⚙️ <b>Admin Panel</b>\n\n")
+
+			if isSuperAdmin(userID) {
+				// Super admin - barcha dorixonalarni ko'radi
+				adminMsg.WriteString("👑 <b>Super Admin</b>\n\n")
 				
-				confirmMsg = 
-					"⚠️ <b>JUDA KATTA DIQQAT!</b>\n\n" +
-					"Siz <b>BARCHA 3 TA DORIXONANING</b> ma'lumotlarini o'chirmoqchisiz:\n\n" +
-					"🗑 Dorixona 1 - barcha dorilar\n" +
-					"🗑 Dorixona 2 - barcha dorilar\n" +
-					"🗑 Dorixona 3 - barcha dorilar\n\n" +
-					"Bu amaliyotni <b>QAYTARIB BO'LMAYDI!</b>\n\n" +
-					"Davom etish uchun:\n" +
-					"<code>/ahad_confirm</code>\n\n" +
-					"Bekor qilish uchun:\n" +
-					"<code>/cancel</code>"
+				for id := 1; id <= 3; id++ {
+					name := getSetting(db, "name", id)
+					phone := getSetting(db, "phone", id)
+					address := getSetting(db, "address", id)
+					
+					if name == "" {
+						name = fmt.Sprintf("Dorixona %d", id)
+					}
+					
+					adminMsg.WriteString(fmt.Sprintf("🏪 <b>%s</b>\n", name))
+					adminMsg.WriteString(fmt.Sprintf("📞 %s\n", phone))
+					adminMsg.WriteString(fmt.Sprintf("📍 %s\n\n", address))
+				}
+				
+				adminMsg.WriteString("<b>O'zgartirish (dorixona raqami bilan):</b>\n")
+				adminMsg.WriteString("🏪 Nom: <code>/setname 1 Markaziy dorixona</code>\n")
+				adminMsg.WriteString("📞 Telefon: <code>/setphone 1 +998901234567</code>\n")
+				adminMsg.WriteString("📍 Manzil: <code>/setaddress 1 https://maps...</code>\n\n")
+				adminMsg.WriteString("<b>Excel yuklash:</b>\n")
+				adminMsg.WriteString("1️⃣ <code>/upload 1</code> - Dorixona 1 tanlash\n")
+				adminMsg.WriteString("2️⃣ Excel faylni yuborish\n")
+				adminMsg.WriteString("📚 Ko'p sheetli workbook: <code>/upload all</code>\n")
+				adminMsg.WriteString("📑 Import profili: <code>/profile 1</code>\n")
+				adminMsg.WriteString("⚙️ Import rejimi: <code>/syncmode 1</code>\n")
+				adminMsg.WriteString("🗂 Import tarixi: <code>/imports 1</code>\n")
+				adminMsg.WriteString("⏰ Muddatlar: <code>/expiry</code> yoki <code>/expiry 1</code>\n")
+				adminMsg.WriteString("🔑 Import API kaliti: <code>/apikey 1</code>\n")
+				adminMsg.WriteString("📦 Eksport: <code>/export 1</code> yoki <code>/export all</code>\n")
+				adminMsg.WriteString("📝 Shablon: <code>/template</code> yoki <code>/template 1</code> (dorilar bilan)\n")
+				adminMsg.WriteString("🚧 Karantin: <code>/quarantine 1</code> (chegara: <code>/quarantine 1 300</code>)\n")
+				adminMsg.WriteString("\n<i>Har safar /upload qilishingiz kerak</i>")
 			} else {
-				// Bitta dorixonani o'chirish
-				pharmacyID, err := strconv.Atoi(target)
+				// Oddiy admin - faqat o'z dorixonasini ko'radi
+				pharmacyID := getPharmacyID(userID)
+				name := getSetting(db, "name", pharmacyID)
+				phone := getSetting(db, "phone", pharmacyID)
+				address := getSetting(db, "address", pharmacyID)
+				
+				if name == "" {
+					name = fmt.Sprintf("Dorixona %d", pharmacyID)
+				}
+				
+				adminMsg.WriteString(fmt.Sprintf("🏪 <b>%s</b>\n\n", name))
+				adminMsg.WriteString(fmt.Sprintf("📞 <b>Telefon:</b> %s\n", phone))
+				adminMsg.WriteString(fmt.Sprintf("📍 <b>Manzil:</b> %s\n\n", address))
+				
+				adminMsg.WriteString("<b>O'zgartirish:</b>\n")
+				adminMsg.WriteString("🏪 Nom: <code>/setname "+name+"</code>\n")
+				adminMsg.WriteString("📞 Telefon: <code>/setphone +998901234567</code>\n")
+				adminMsg.WriteString("📍 Manzil: <code>/setaddress https://maps...</code>\n\n")
+				adminMsg.WriteString("📊 Excel yuklash: Faylni yuboring (.xlsx, .xls, .csv, 1C .xml/.zip)\n")
+				adminMsg.WriteString("📑 Import profili: <code>/profile</code>\n")
+				adminMsg.WriteString("⚙️ Import rejimi: <code>/syncmode</code>\n")
+				adminMsg.WriteString("🗂 Import tarixi: <code>/imports</code>\n")
+				adminMsg.WriteString("⏰ Muddatlar: <code>/expiry</code>\n")
+				adminMsg.WriteString("🔑 Import API kaliti: <code>/apikey</code>\n")
+				adminMsg.WriteString("📦 Eksport: <code>/export</code>\n")
+				adminMsg.WriteString("📝 Shablon: <code>/template</code> yoki <code>/template fill</code> (dorilar bilan)\n")
+				adminMsg.WriteString("🚧 Karantin: <code>/quarantine</code> (chegara: <code>/quarantine 300</code>)")
+			}
+
+			msg := tgbotapi.NewMessage(update.Message.Chat.ID, adminMsg.String())
+			msg.ParseMode = "HTML"
+			bot.Send(msg)
+			continue
+		}
+
+		// /setname - dorixona nomini o'zgartirish
+		if strings.HasPrefix(update.Message.Text, "/setname ") {
+			if !isAdmin(userID) {
+				bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "❌ Siz admin emassiz"))
+				continue
+			}
+
+			input := strings.TrimPrefix(update.Message.Text, "/setname ")
+			input = strings.TrimSpace(input)
+			
+			var pharmacyID int
+			var newName string
+			
+			if isSuperAdmin(userID) {
+				// Super admin: /setname 1 Markaziy dorixona
+				parts := strings.SplitN(input, " ", 2)
+				if len(parts) < 2 {
+					bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, 
+						"❌ Noto'g'ri format\n\n"+
+						"To'g'ri format:\n"+
+						"<code>/setname 1 Markaziy Dorixona</code>\n"+
+						"<code>/setname 2 Chilonzor Filiali</code>"))
+					continue
+				}
+				
+				var err error
+				pharmacyID, err = strconv.Atoi(parts[0])
 				if err != nil || pharmacyID < 1 || pharmacyID > 3 {
-					bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "❌ Dorixona raqami 1, 2, 3 yoki 'all' bo'lishi kerak"))
+					bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "❌ Dorixona raqami 1, 2 yoki 3 bo'lishi kerak"))
+					continue
+				}
+				newName = strings.TrimSpace(parts[1])
+			} else {
+				// Oddiy admin: /setname Markaziy dorixona
+				pharmacyID = getPharmacyID(userID)
+				newName = input
+				
+				if newName == "" {
+					bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, 
+						"❌ Noto'g'ri format\n\n"+
+						"To'g'ri format:\n"+
+						"<code>/setname Markaziy Dorixona</code>"))
 					continue
 				}
+			}
 
-				uploadSession[userID] = pharmacyID // Session'da saqlab qo'yish
+			err := updateSetting(db, "name", newName, pharmacyID)
+			if err != nil {
+				bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "❌ Xatolik: "+err.Error()))
+				continue
+			}
+
+			msg := tgbotapi.NewMessage(update.Message.Chat.ID, 
+				fmt.Sprintf("✅ Dorixona %d nomi yangilandi!\n\n🏪 Yangi nom: <b>%s</b>", pharmacyID, newName))
+			msg.ParseMode = "HTML"
+			bot.Send(msg)
+			continue
+		}
+
+		// /setphone - telefon o'zgartirish
+		if strings.HasPrefix(update.Message.Text, "/setphone ") {
+			if !isAdmin(userID) {
+				bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "❌ Siz admin emassiz"))
+				continue
+			}
+
+			input := strings.TrimPrefix(update.Message.Text, "/setphone ")
+			input = strings.TrimSpace(input)
+			
+			var pharmacyID int
+			var newPhone string
+			
+			if isSuperAdmin(userID) {
+				// Super admin: /setphone 1 +998901234567
+				parts := strings.SplitN(input, " ", 2)
+				if len(parts) < 2 {
+					bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, 
+						"❌ Noto'g'ri format\n\n"+
+						"To'g'ri format:\n"+
+						"<code>/setphone 1 +998901234567</code>"))
+					continue
+				}
 				
-				pharmacyName := getSetting(db, "name", pharmacyID)
-				if pharmacyName == "" {
-					pharmacyName = fmt.Sprintf("Dorixona %d", pharmacyID)
+				var err error
+				pharmacyID, err = strconv.Atoi(parts[0])
+				if err != nil || pharmacyID < 1 || pharmacyID > 3 {
+					bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "❌ Dorixona raqami 1, 2 yoki 3 bo'lishi kerak"))
+					continue
+				}
+				newPhone = strings.TrimSpace(parts[1])
+			} else {
+				// Oddiy admin: /setphone +998901234567
+				pharmacyID = getPharmacyID(userID)
+				newPhone = input
+				
+				if newPhone == "" {
+					bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, 
+						"❌ Noto'g'ri format\n\n"+
+						"To'g'ri format:\n"+
+						"<code>/setphone +998901234567</code>"))
+					continue
 				}
+			}
 
-				confirmMsg = fmt.Sprintf(
-					"⚠️ <b>DIQQAT!</b>\n\n"+
-					"Siz <b>%s</b> ning barcha dorilarini o'chirmoqchisiz\n\n"+
-					"🗑 Barcha dorilar o'chiriladi\n\n"+
-					"Bu amaliyotni <b>QAYTARIB BO'LMAYDI!</b>\n\n"+
-					"Davom etish uchun:\n"+
-					"<code>/ahad_confirm</code>\n\n"+
-					"Bekor qilish uchun:\n"+
-					"<code>/cancel</code>",
-					pharmacyName,
-				)
+			err := updateSetting(db, "phone", newPhone, pharmacyID)
+			if err != nil {
+				bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "❌ Xatolik: "+err.Error()))
+				continue
 			}
 
-			msg := tgbotapi.NewMessage(update.Message.Chat.ID, confirmMsg)
+			// Barcha dorilarni yangilash
+			_, err = db.Exec("UPDATE medicines SET phone = $1 WHERE pharmacy_id = $2", newPhone, pharmacyID)
+			if err != nil {
+				bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "⚠️ Settings yangilandi, lekin dorilar yangilanmadi"))
+			}
+
+			msg := tgbotapi.NewMessage(update.Message.Chat.ID, 
+				fmt.Sprintf("✅ Dorixona %d telefoni yangilandi!\n\n📞 Yangi: <code>%s</code>", pharmacyID, newPhone))
+			msg.ParseMode = "HTML"
+			bot.Send(msg)
+			continue
+		}
+
+		// /setaddress - manzil o'zgartirish
+		if strings.HasPrefix(update.Message.Text, "/setaddress ") {
+			if !isAdmin(userID) {
+				bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "❌ Siz admin emassiz"))
+				continue
+			}
+
+			input := strings.TrimPrefix(update.Message.Text, "/setaddress ")
+			input = strings.TrimSpace(input)
+			
+			var pharmacyID int
+			var newAddress string
+			
+			if isSuperAdmin(userID) {
+				// Super admin: /setaddress 1 Toshkent, Amir Temur
+				parts := strings.SplitN(input, " ", 2)
+				if len(parts) < 2 {
+					bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, 
+						"❌ Noto'g'ri format\n\n"+
+						"To'g'ri format:\n"+
+						"<code>/setaddress 1 Toshkent, Amir Temur 123</code>\n"+
+						"<code>/setaddress 1 https://maps.app.goo.gl/xxx</code>"))
+					continue
+				}
+				
+				var err error
+				pharmacyID, err = strconv.Atoi(parts[0])
+				if err != nil || pharmacyID < 1 || pharmacyID > 3 {
+					bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "❌ Dorixona raqami 1, 2 yoki 3 bo'lishi kerak"))
+					continue
+				}
+				newAddress = strings.TrimSpace(parts[1])
+			} else {
+				// Oddiy admin: /setaddress Toshkent, Amir Temur
+				pharmacyID = getPharmacyID(userID)
+				newAddress = input
+				
+				if newAddress == "" {
+					bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, 
+						"❌ Noto'g'ri format\n\n"+
+						"To'g'ri format:\n"+
+						"<code>/setaddress Toshkent, Amir Temur 123</code>\n"+
+						"<code>/setaddress https://maps.app.goo.gl/xxx</code>"))
+					continue
+				}
+			}
+
+			err := updateSetting(db, "address", newAddress, pharmacyID)
+			if err != nil {
+				bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "❌ Xatolik: "+err.Error()))
+				continue
+			}
+
+			// Barcha dorilarni yangilash
+			_, err = db.Exec("UPDATE medicines SET address = $1 WHERE pharmacy_id = $2", newAddress, pharmacyID)
+			if err != nil {
+				bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "⚠️ Settings yangilandi, lekin dorilar yangilanmadi"))
+			}
+
+			msg := tgbotapi.NewMessage(update.Message.Chat.ID, 
+				fmt.Sprintf("✅ Dorixona %d manzili yangilandi!\n\n📍 Yangi: %s", pharmacyID, newAddress))
 			msg.ParseMode = "HTML"
 			bot.Send(msg)
 			continue
 		}
 
-		// /ahad_confirm - Tasdiqlangan o'chirish
-		if update.Message.Text == "/ahad_confirm" {
+		// /profile - import profilini ko'rish
+		if update.Message.Text == "/profile" || strings.HasPrefix(update.Message.Text, "/profile ") {
 			if !isAdmin(userID) {
 				bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "❌ Siz admin emassiz"))
 				continue
 			}
 
-			var pharmacyID int
-			var deleteAll bool
+			pharmacyID, _, err := resolvePharmacy(userID, strings.TrimPrefix(update.Message.Text, "/profile"))
+			if err != nil {
+				bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "❌ "+err.Error()+"\n\nTo'g'ri format: /profile 1"))
+				continue
+			}
+
+			profile, err := excel.LoadProfile(db, pharmacyID)
+			if err != nil {
+				bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "❌ Xatolik: "+err.Error()))
+				continue
+			}
 
+			prefix := ""
 			if isSuperAdmin(userID) {
-				// Super admin uchun session'dan olish
-				if pid, ok := uploadSession[userID]; ok {
-					if pid == -1 {
-						deleteAll = true
-					} else {
-						pharmacyID = pid
-					}
-					delete(uploadSession, userID) // Session tozalash
-				} else {
-					bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, 
-						"❌ Avval /ahad 1, /ahad 2, /ahad 3 yoki /ahad all ni tanlang"))
-					continue
-				}
+				prefix = fmt.Sprintf("%d ", pharmacyID)
+			}
+
+			var profileMsg strings.Builder
+			profileMsg.WriteString(fmt.Sprintf("📑 <b>Dorixona %d import profili</b>\n\n", pharmacyID))
+			if profile == nil {
+				profileMsg.WriteString("Profil yo'q - ustunlar sarlavha bo'yicha avtomatik aniqlanadi\n\n")
 			} else {
-				// Oddiy admin - faqat o'z dorixonasi
-				pharmacyID = getPharmacyID(userID)
+				profileMsg.WriteString(profile.String() + "\n")
 			}
+			profileMsg.WriteString("<b>Sozlash:</b>\n")
+			profileMsg.WriteString("<code>/setprofile " + prefix + "sheet=Лист1 header=3 num=A name=B count=D price=E manufacturer=G barcode=H series=I expiry=J category=K decimal=, skip=4,5</code>\n\n")
+			profileMsg.WriteString("• Ustunlar harf (B) yoki raqam (2) bilan\n")
+			profileMsg.WriteString("• Sheet nomidagi bo'sh joy o'rniga _ yozing\n")
+			profileMsg.WriteString("• Kerakli sozlamalarni yozish kifoya\n\n")
+			profileMsg.WriteString("O'chirish: <code>/delprofile" + strings.TrimSuffix(" "+prefix, " ") + "</code>")
+
+			msg := tgbotapi.NewMessage(update.Message.Chat.ID, profileMsg.String())
+			msg.ParseMode = "HTML"
+			bot.Send(msg)
+			continue
+		}
 
-			var medicinesCount int64
-			var successMsg string
+		// /setprofile - import profilini saqlash
+		if strings.HasPrefix(update.Message.Text, "/setprofile ") {
+			if !isAdmin(userID) {
+				bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "❌ Siz admin emassiz"))
+				continue
+			}
 
-			if deleteAll {
-				// Barcha dorixonalarning dorilarini o'chirish
-				db.QueryRow("SELECT COUNT(*) FROM medicines").Scan(&medicinesCount)
-				
-				_, err := db.Exec("TRUNCATE TABLE medicines RESTART IDENTITY")
-				if err != nil {
-					bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "❌ O'chirishda xato: "+err.Error()))
-					continue
-				}
+			pharmacyID, input, err := resolvePharmacy(userID, strings.TrimPrefix(update.Message.Text, "/setprofile "))
+			if err != nil {
+				bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "❌ "+err.Error()))
+				continue
+			}
 
-				successMsg = fmt.Sprintf(
-					"✅ <b>Barcha dorixonalar tozalandi!</b>\n\n"+
-					"🗑 O'chirilgan dorilar: <b>%d</b> ta\n\n"+
-					"📝 Endi qaytadan Excel yuklashingiz mumkin",
-					medicinesCount,
-				)
-			} else {
-				// Bitta dorixonaning dorilarini o'chirish
-				db.QueryRow("SELECT COUNT(*) FROM medicines WHERE pharmacy_id = $1", pharmacyID).Scan(&medicinesCount)
-				
-				_, err := db.Exec("DELETE FROM medicines WHERE pharmacy_id = $1", pharmacyID)
+			profile, err := excel.ParseProfile(input)
+			if err != nil || input == "" {
+				errText := "sozlamalar kiritilmagan"
 				if err != nil {
-					bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "❌ O'chirishda xato: "+err.Error()))
-					continue
-				}
-
-				pharmacyName := getSetting(db, "name", pharmacyID)
-				if pharmacyName == "" {
-					pharmacyName = fmt.Sprintf("Dorixona %d", pharmacyID)
+					errText = err.Error()
 				}
+				bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "❌ Noto'g'ri format: "+errText+"\n\nNamuna uchun: /profile"))
+				continue
+			}
 
-				successMsg = fmt.Sprintf(
-					"✅ <b>%s tozalandi!</b>\n\n"+
-					"🗑 O'chirilgan dorilar: <b>%d</b> ta\n\n"+
-					"📝 Endi qaytadan Excel yuklashingiz mumkin",
-					pharmacyName, medicinesCount,
-				)
+			if err := updateSetting(db, excel.ProfileSettingKey, profile.JSON(), pharmacyID); err != nil {
+				bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "❌ Xatolik: "+err.Error()))
+				continue
 			}
 
-			msg := tgbotapi.NewMessage(update.Message.Chat.ID, successMsg)
+			msg := tgbotapi.NewMessage(update.Message.Chat.ID,
+				fmt.Sprintf("✅ Dorixona %d import profili saqlandi!\n\n%s", pharmacyID, profile.String()))
 			msg.ParseMode = "HTML"
 			bot.Send(msg)
 			continue
 		}
 
-		// /cancel - Bekor qilish
-		if update.Message.Text == "/cancel" {
-			// Session tozalash
-			delete(uploadSession, userID)
-			bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "✅ Bekor qilindi"))
+		// /delprofile - import profilini o'chirish
+		if update.Message.Text == "/delprofile" || strings.HasPrefix(update.Message.Text, "/delprofile ") {
+			if !isAdmin(userID) {
+				bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "❌ Siz admin emassiz"))
+				continue
+			}
+
+			pharmacyID, _, err := resolvePharmacy(userID, strings.TrimPrefix(update.Message.Text, "/delprofile"))
+			if err != nil {
+				bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "❌ "+err.Error()+"\n\nTo'g'ri format: /delprofile 1"))
+				continue
+			}
+
+			if err := deleteSetting(db, excel.ProfileSettingKey, pharmacyID); err != nil {
+				bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "❌ Xatolik: "+err.Error()))
+				continue
+			}
+
+			bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID,
+				fmt.Sprintf("✅ Dorixona %d import profili o'chirildi\n\nUstunlar endi avtomatik aniqlanadi", pharmacyID)))
 			continue
 		}
 
-		// /admin - admin panel
-		if update.Message.Text == "/admin" {
+		// /syncmode - standart import rejimi (merge yoki replace)
+		if update.Message.Text == "/syncmode" || strings.HasPrefix(update.Message.Text, "/syncmode ") {
 			if !isAdmin(userID) {
 				bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "❌ Siz admin emassiz"))
 				continue
 			}
 
-			var adminMsg strings.Builder
-			adminMsg.WriteString("⚙️ <b>Admin Panel</b>\n\n")
+			pharmacyID, mode, err := resolvePharmacy(userID, strings.TrimPrefix(update.Message.Text, "/syncmode"))
+			if err != nil {
+				bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "❌ "+err.Error()+"\n\nTo'g'ri format: /syncmode 1 replace"))
+				continue
+			}
 
+			prefix := ""
 			if isSuperAdmin(userID) {
-				// Super admin - barcha dorixonalarni ko'radi
-				adminMsg.WriteString("👑 <b>Super Admin</b>\n\n")
-				
-				for id := 1; id <= 3; id++ {
-					name := getSetting(db, "name", id)
-					phone := getSetting(db, "phone", id)
-					address := getSetting(db, "address", id)
-					
-					if name == "" {
-						name = fmt.Sprintf("Dorixona %d", id)
-					}
-					
-					adminMsg.WriteString(fmt.Sprintf("🏪 <b>%s</b>\n", name))
-					adminMsg.WriteString(fmt.Sprintf("📞 %s\n", phone))
-					adminMsg.WriteString(fmt.Sprintf("📍 %s\n\n", address))
-				}
-				
-				adminMsg.WriteString("<b>O'zgartirish (dorixona raqami bilan):</b>\n")
-				adminMsg.WriteString("🏪 Nom: <code>/setname 1 Markaziy dorixona</code>\n")
-				adminMsg.WriteString("📞 Telefon: <code>/setphone 1 +998901234567</code>\n")
-				adminMsg.WriteString("📍 Manzil: <code>/setaddress 1 https://maps...</code>\n\n")
-				adminMsg.WriteString("<b>Excel yuklash:</b>\n")
-				adminMsg.WriteString("1️⃣ <code>/upload 1</code> - Dorixona 1 tanlash\n")
-				adminMsg.WriteString("2️⃣ Excel faylni yuborish\n")
-				adminMsg.WriteString("\n<i>Har safar /upload qilishingiz kerak</i>")
-			} else {
-				// Oddiy admin - faqat o'z dorixonasini ko'radi
-				pharmacyID := getPharmacyID(userID)
-				name := getSetting(db, "name", pharmacyID)
-				phone := getSetting(db, "phone", pharmacyID)
-				address := getSetting(db, "address", pharmacyID)
-				
-				if name == "" {
-					name = fmt.Sprintf("Dorixona %d", pharmacyID)
-				}
-				
-				adminMsg.WriteString(fmt.Sprintf("🏪 <b>%s</b>\n\n", name))
-				adminMsg.WriteString(fmt.Sprintf("📞 <b>Telefon:</b> %s\n", phone))
-				adminMsg.WriteString(fmt.Sprintf("📍 <b>Manzil:</b> %s\n\n", address))
-				
-				adminMsg.WriteString("<b>O'zgartirish:</b>\n")
-				adminMsg.WriteString("🏪 Nom: <code>/setname "+name+"</code>\n")
-				adminMsg.WriteString("📞 Telefon: <code>/setphone +998901234567</code>\n")
-				adminMsg.WriteString("📍 Manzil: <code>/setaddress https://maps...</code>\n\n")
-				adminMsg.WriteString("📊 Excel yuklash: Faylni yuboring")
+				prefix = fmt.Sprintf("%d ", pharmacyID)
 			}
 
-			msg := tgbotapi.NewMessage(update.Message.Chat.ID, adminMsg.String())
+			if mode == "" {
+				msg := tgbotapi.NewMessage(update.Message.Chat.ID, fmt.Sprintf(
+					"⚙️ Dorixona %d import rejimi: <b>%s</b>\n\n"+
+						"<code>/syncmode %smerge</code> - faqat qo'shish va yangilash\n"+
+						"<code>/syncmode %sreplace</code> - to'liq sinxron (faylda yo'q dorilar o'chiriladi)",
+					pharmacyID, importModeName(excel.LoadMode(db, pharmacyID)), prefix, prefix))
+				msg.ParseMode = "HTML"
+				bot.Send(msg)
+				continue
+			}
+
+			if mode != excel.ModeMerge && mode != excel.ModeReplace {
+				bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "❌ Rejim merge yoki replace bo'lishi kerak"))
+				continue
+			}
+
+			if err := updateSetting(db, excel.ModeSettingKey, mode, pharmacyID); err != nil {
+				bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "❌ Xatolik: "+err.Error()))
+				continue
+			}
+
+			msg := tgbotapi.NewMessage(update.Message.Chat.ID,
+				fmt.Sprintf("✅ Dorixona %d import rejimi: <b>%s</b>", pharmacyID, importModeName(mode)))
 			msg.ParseMode = "HTML"
 			bot.Send(msg)
 			continue
 		}
 
-		// /setname - dorixona nomini o'zgartirish
-		if strings.HasPrefix(update.Message.Text, "/setname ") {
+		// /quarantine - karantindagi dorilar va narx sakrashi chegarasi
+		if update.Message.Text == "/quarantine" || strings.HasPrefix(update.Message.Text, "/quarantine ") {
 			if !isAdmin(userID) {
 				bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "❌ Siz admin emassiz"))
 				continue
 			}
 
-			input := strings.TrimPrefix(update.Message.Text, "/setname ")
-			input = strings.TrimSpace(input)
-			
-			var pharmacyID int
-			var newName string
-			
-			if isSuperAdmin(userID) {
-				// Super admin: /setname 1 Markaziy dorixona
-				parts := strings.SplitN(input, " ", 2)
-				if len(parts) < 2 {
-					bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, 
-						"❌ Noto'g'ri format\n\n"+
-						"To'g'ri format:\n"+
-						"<code>/setname 1 Markaziy Dorixona</code>\n"+
-						"<code>/setname 2 Chilonzor Filiali</code>"))
+			pharmacyID, percent, err := resolvePharmacy(userID, strings.TrimPrefix(update.Message.Text, "/quarantine"))
+			if err != nil {
+				bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "❌ "+err.Error()+"\n\nTo'g'ri format: /quarantine 1 yoki /quarantine 1 300"))
+				continue
+			}
+
+			// Chegara berilgan bo'lsa - sozlamani yangilash
+			if percent != "" {
+				value, err := excel.ParsePriceJump(percent)
+				if err != nil {
+					bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "❌ "+err.Error()))
 					continue
 				}
-				
+				if err := updateSetting(db, excel.PriceJumpSettingKey, strconv.Itoa(value), pharmacyID); err != nil {
+					bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "❌ Xatolik: "+err.Error()))
+					continue
+				}
+				msg := tgbotapi.NewMessage(update.Message.Chat.ID, fmt.Sprintf(
+					"✅ Dorixona %d narx sakrashi chegarasi: <b>%d%%</b>\nNarx shundan ko'proq o'zgarsa dori karantinga tushadi", pharmacyID, value))
+				msg.ParseMode = "HTML"
+				bot.Send(msg)
+				continue
+			}
+
+			text, keyboard, err := quarantineMessage(db, pharmacyID)
+			if err != nil {
+				fmt.Println("error:", err)
+				bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "❌ DB error: "+err.Error()))
+				continue
+			}
+			msg := tgbotapi.NewMessage(update.Message.Chat.ID, text)
+			msg.ParseMode = "HTML"
+			if keyboard != nil {
+				msg.ReplyMarkup = keyboard
+			}
+			bot.Send(msg)
+			continue
+		}
+
+		// /template - import shabloni (bo'sh yoki dorixona dorilari bilan to'ldirilgan)
+		if update.Message.Text == "/template" || strings.HasPrefix(update.Message.Text, "/template ") {
+			if !isAdmin(userID) {
+				bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "❌ Siz admin emassiz"))
+				continue
+			}
+
+			args := strings.TrimSpace(strings.TrimPrefix(update.Message.Text, "/template"))
+			pharmacyID := 0
+			if args != "" {
+				// Oddiy admin: /template fill, super admin: /template 1
+				var rest string
 				var err error
-				pharmacyID, err = strconv.Atoi(parts[0])
-				if err != nil || pharmacyID < 1 || pharmacyID > 3 {
-					bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "❌ Dorixona raqami 1, 2 yoki 3 bo'lishi kerak"))
+				pharmacyID, rest, err = resolvePharmacy(userID, args)
+				if err == nil && !isSuperAdmin(userID) && rest != "fill" {
+					err = fmt.Errorf("noma'lum parametr: %s", rest)
+				}
+				if err != nil {
+					bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "❌ "+err.Error()+"\n\nTo'g'ri format: /template yoki /template fill (super admin: /template 1)"))
 					continue
 				}
-				newName = strings.TrimSpace(parts[1])
+			}
+
+			data, err := excel.Template(db, pharmacyID)
+			if err != nil {
+				fmt.Println("error:", err)
+				bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "❌ Shablon yaratilmadi: "+err.Error()))
+				continue
+			}
+
+			caption := "📝 Import shabloni\nTo'ldirib botga yuboring. Yo'riqnoma ikkinchi sheetda"
+			if pharmacyID > 0 {
+				caption = fmt.Sprintf("📝 Dorixona %d joriy dorilari\nTahrirlab botga qayta yuboring", pharmacyID)
+			}
+			// Saqlangan profil ustunlari shablon tuzilishidan farq qilishi mumkin
+			target := pharmacyID
+			if target == 0 {
+				target = getPharmacyID(userID)
+			}
+			if profile, err := excel.LoadProfile(db, target); err == nil && profile != nil && len(profile.Columns) > 0 {
+				caption += "\n\n⚠️ Import profilida ustunlar qo'lda berilgan - shablonni yuklashdan oldin profilni o'chiring (/delprofile)"
+			}
+
+			doc := tgbotapi.NewDocument(update.Message.Chat.ID, tgbotapi.FileBytes{
+				Name:  excel.TemplateFileName(pharmacyID),
+				Bytes: data,
+			})
+			doc.Caption = caption
+			bot.Send(doc)
+			continue
+		}
+
+		// /export - dorilar ro'yxatini Excel fayl sifatida olish
+		if update.Message.Text == "/export" || strings.HasPrefix(update.Message.Text, "/export ") {
+			if !isAdmin(userID) {
+				bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "❌ Siz admin emassiz"))
+				continue
+			}
+
+			args := strings.TrimSpace(strings.TrimPrefix(update.Message.Text, "/export"))
+			var pharmacyIDs []int
+			scope := ""
+			if isSuperAdmin(userID) && (args == "" || args == "all") {
+				// Super admin - har bir dorixona alohida sheetda
+				pharmacyIDs = []int{1, 2, 3}
+				scope = "all"
 			} else {
-				// Oddiy admin: /setname Markaziy dorixona
-				pharmacyID = getPharmacyID(userID)
-				newName = input
-				
-				if newName == "" {
-					bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, 
-						"❌ Noto'g'ri format\n\n"+
-						"To'g'ri format:\n"+
-						"<code>/setname Markaziy Dorixona</code>"))
+				pharmacyID, rest, err := resolvePharmacy(userID, args)
+				// Oddiy admin boshqa dorixona raqamini yozsa jim o'z dorixonasi berilmaydi
+				if err == nil && !isSuperAdmin(userID) && rest != "" && rest != strconv.Itoa(pharmacyID) {
+					err = fmt.Errorf("faqat o'z dorixonangizni (%d) eksport qila olasiz", pharmacyID)
+				}
+				if err != nil {
+					bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "❌ "+err.Error()+"\n\nTo'g'ri format: /export 1 yoki /export all"))
+					continue
+				}
+				pharmacyIDs = []int{pharmacyID}
+				scope = strconv.Itoa(pharmacyID)
+			}
+
+			data, err := excel.ExportInventory(db, pharmacyIDs, getAllPharmacies(db))
+			if err != nil {
+				fmt.Println("error:", err)
+				bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "❌ Eksportda xato: "+err.Error()))
+				continue
+			}
+
+			doc := tgbotapi.NewDocument(update.Message.Chat.ID, tgbotapi.FileBytes{
+				Name:  excel.ExportFileName(scope),
+				Bytes: data,
+			})
+			doc.Caption = "📦 Joriy dorilar ro'yxati"
+			bot.Send(doc)
+			continue
+		}
+
+		// /apikey - back-office import API kaliti (yangi kalit yoki o'chirish)
+		if update.Message.Text == "/apikey" || strings.HasPrefix(update.Message.Text, "/apikey ") {
+			if !isAdmin(userID) {
+				bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "❌ Siz admin emassiz"))
+				continue
+			}
+
+			pharmacyID, action, err := resolvePharmacy(userID, strings.TrimPrefix(update.Message.Text, "/apikey"))
+			if err != nil {
+				bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "❌ "+err.Error()+"\n\nTo'g'ri format: /apikey 1"))
+				continue
+			}
+
+			if action == "off" {
+				if err := deleteSetting(db, apiKeySettingKey, pharmacyID); err != nil {
+					bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "❌ Xatolik: "+err.Error()))
 					continue
 				}
+				bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, fmt.Sprintf("✅ Dorixona %d API kaliti o'chirildi", pharmacyID)))
+				continue
+			}
+			if action != "" {
+				bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "❌ Noma'lum buyruq. /apikey yoki /apikey off"))
+				continue
 			}
 
-			err := updateSetting(db, "name", newName, pharmacyID)
+			// Har safar yangi kalit - eskisi bekor bo'ladi
+			key, err := generateAPIKey()
+			if err == nil {
+				err = updateSetting(db, apiKeySettingKey, hashAPIKey(key), pharmacyID)
+			}
 			if err != nil {
 				bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "❌ Xatolik: "+err.Error()))
 				continue
 			}
 
-			msg := tgbotapi.NewMessage(update.Message.Chat.ID, 
-				fmt.Sprintf("✅ Dorixona %d nomi yangilandi!\n\n🏪 Yangi nom: <b>%s</b>", pharmacyID, newName))
+			prefix := ""
+			if isSuperAdmin(userID) {
+				prefix = fmt.Sprintf("%d ", pharmacyID)
+			}
+			msg := tgbotapi.NewMessage(update.Message.Chat.ID, fmt.Sprintf(
+				"🔑 <b>Dorixona %d API kaliti</b>\n\n"+
+					"<code>%s</code>\n\n"+
+					"⚠️ Kalit faqat hozir ko'rsatiladi, eski kalit bekor qilindi\n\n"+
+					"<b>Yuklash:</b>\n"+
+					"<code>curl -H \"Authorization: Bearer %s\" -F file=@narxlar.xlsx -F mode=merge https://.../api/pharmacies/%d/imports</code>\n\n"+
+					"O'chirish: <code>/apikey %soff</code>",
+				pharmacyID, key, key, pharmacyID, prefix))
 			msg.ParseMode = "HTML"
 			bot.Send(msg)
 			continue
 		}
 
-		// /setphone - telefon o'zgartirish
-		if strings.HasPrefix(update.Message.Text, "/setphone ") {
+		// /expiry - yaroqlilik muddatlari hisoboti va ogohlantirish oynalari
+		if update.Message.Text == "/expiry" || strings.HasPrefix(update.Message.Text, "/expiry ") {
 			if !isAdmin(userID) {
 				bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "❌ Siz admin emassiz"))
 				continue
 			}
 
-			input := strings.TrimPrefix(update.Message.Text, "/setphone ")
-			input = strings.TrimSpace(input)
-			
-			var pharmacyID int
-			var newPhone string
-			
+			args := strings.TrimSpace(strings.TrimPrefix(update.Message.Text, "/expiry"))
+
+			// Super admin - barcha dorixonalar bo'yicha umumiy hisobot
+			if isSuperAdmin(userID) && (args == "" || args == "all") {
+				batches, windows := collectExpiry(db)
+				sendExpiryDigest(bot, update.Message.Chat.ID, getAllPharmacies(db), batches, windows)
+				continue
+			}
+
+			pharmacyID, windowsArg, err := resolvePharmacy(userID, args)
+			if err != nil {
+				bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "❌ "+err.Error()+"\n\nTo'g'ri format: /expiry 1 30,60,90"))
+				continue
+			}
+
+			prefix := ""
 			if isSuperAdmin(userID) {
-				// Super admin: /setphone 1 +998901234567
-				parts := strings.SplitN(input, " ", 2)
-				if len(parts) < 2 {
-					bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, 
-						"❌ Noto'g'ri format\n\n"+
-						"To'g'ri format:\n"+
-						"<code>/setphone 1 +998901234567</code>"))
+				prefix = fmt.Sprintf("%d ", pharmacyID)
+			}
+
+			// Oynalarni sozlash
+			if windowsArg != "" {
+				windows, err := excel.ParseExpiryWindows(windowsArg)
+				if err != nil {
+					bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "❌ "+err.Error()))
 					continue
 				}
-				
-				var err error
-				pharmacyID, err = strconv.Atoi(parts[0])
-				if err != nil || pharmacyID < 1 || pharmacyID > 3 {
-					bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "❌ Dorixona raqami 1, 2 yoki 3 bo'lishi kerak"))
-					continue
+				parts := make([]string, len(windows))
+				for i, w := range windows {
+					parts[i] = strconv.Itoa(w)
 				}
-				newPhone = strings.TrimSpace(parts[1])
-			} else {
-				// Oddiy admin: /setphone +998901234567
-				pharmacyID = getPharmacyID(userID)
-				newPhone = input
-				
-				if newPhone == "" {
-					bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, 
-						"❌ Noto'g'ri format\n\n"+
-						"To'g'ri format:\n"+
-						"<code>/setphone +998901234567</code>"))
+				if err := updateSetting(db, excel.ExpiryWindowsSettingKey, strings.Join(parts, ","), pharmacyID); err != nil {
+					bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "❌ Xatolik: "+err.Error()))
 					continue
 				}
+				msg := tgbotapi.NewMessage(update.Message.Chat.ID, fmt.Sprintf(
+					"✅ Dorixona %d ogohlantirish muddatlari: <b>%s</b> kun", pharmacyID, strings.Join(parts, ", ")))
+				msg.ParseMode = "HTML"
+				bot.Send(msg)
+				continue
 			}
 
-			err := updateSetting(db, "phone", newPhone, pharmacyID)
+			batches, windows, err := loadExpiringBatches(db, pharmacyID)
 			if err != nil {
 				bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "❌ Xatolik: "+err.Error()))
 				continue
 			}
+			names := getAllPharmacies(db)
+			text := formatExpiryAlert(pharmacyDisplayName(names, pharmacyID), batches, windows) +
+				fmt.Sprintf("\n⚙️ Oynalarni o'zgartirish: <code>/expiry %s30,60,90</code>", prefix)
+			sendExpiryReport(bot, update.Message.Chat.ID, text, batches, map[int][]int{pharmacyID: windows}, names,
+				fmt.Sprintf("muddatlar_%d_%s.xlsx", pharmacyID, time.Now().In(alertLocation).Format("2006-01-02")))
+			continue
+		}
+
+		// /imports - oxirgi yuklangan fayllar tarixi
+		if update.Message.Text == "/imports" || strings.HasPrefix(update.Message.Text, "/imports ") {
+			if !isAdmin(userID) {
+				bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "❌ Siz admin emassiz"))
+				continue
+			}
 
-			// Barcha dorilarni yangilash
-			_, err = db.Exec("UPDATE medicines SET phone = $1 WHERE pharmacy_id = $2", newPhone, pharmacyID)
+			pharmacyID, _, err := resolvePharmacy(userID, strings.TrimPrefix(update.Message.Text, "/imports"))
 			if err != nil {
-				bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "⚠️ Settings yangilandi, lekin dorilar yangilanmadi"))
+				bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "❌ "+err.Error()+"\n\nTo'g'ri format: /imports 1"))
+				continue
 			}
 
-			msg := tgbotapi.NewMessage(update.Message.Chat.ID, 
-				fmt.Sprintf("✅ Dorixona %d telefoni yangilandi!\n\n📞 Yangi: <code>%s</code>", pharmacyID, newPhone))
+			records, err := excel.ListImports(db, pharmacyID, 10)
+			if err != nil {
+				bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "❌ DB error: "+err.Error()))
+				continue
+			}
+
+			if len(records) == 0 {
+				bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, fmt.Sprintf("📭 Dorixona %d uchun importlar yo'q", pharmacyID)))
+				continue
+			}
+
+			var importsMsg strings.Builder
+			importsMsg.WriteString(fmt.Sprintf("🗂 <b>Dorixona %d - oxirgi importlar</b>\n\n", pharmacyID))
+			for _, rec := range records {
+				importsMsg.WriteString(fmt.Sprintf("🆔 <b>#%d</b> - %s\n", rec.ID, rec.CreatedAt.Format("02.01.2006 15:04")))
+				importsMsg.WriteString(fmt.Sprintf("📁 <code>%s</code>\n", html.EscapeString(rec.FileName)))
+				importsMsg.WriteString(fmt.Sprintf("👤 %d | ⚙️ %s\n", rec.UploadedBy, importModeName(rec.Mode)))
+				importsMsg.WriteString(fmt.Sprintf("✅ %d (🆕 %d) | 🗑 %d | ⏭ %d | 🔄 %d\n",
+					rec.Saved, rec.Inserted, rec.Removed, rec.Skipped, rec.Duplicates))
+				if len(rec.Checksum) >= 12 {
+					importsMsg.WriteString(fmt.Sprintf("🔑 <code>%s</code>\n", rec.Checksum[:12]))
+				}
+				if rec.RolledBackAt != nil {
+					importsMsg.WriteString(fmt.Sprintf("↩️ Bekor qilingan: %s\n", rec.RolledBackAt.Format("02.01.2006 15:04")))
+				}
+				importsMsg.WriteString("\n")
+			}
+			importsMsg.WriteString("↩️ Import oldidagi holatga qaytish: <code>/rollback ID</code>")
+
+			msg := tgbotapi.NewMessage(update.Message.Chat.ID, importsMsg.String())
 			msg.ParseMode = "HTML"
 			bot.Send(msg)
 			continue
 		}
 
-		// /setaddress - manzil o'zgartirish
-		if strings.HasPrefix(update.Message.Text, "/setaddress ") {
+		// /rollback - dorixonani import oldidagi holatga qaytarish
+		if strings.HasPrefix(update.Message.Text, "/rollback ") {
 			if !isAdmin(userID) {
 				bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "❌ Siz admin emassiz"))
 				continue
 			}
 
-			input := strings.TrimPrefix(update.Message.Text, "/setaddress ")
-			input = strings.TrimSpace(input)
-			
-			var pharmacyID int
-			var newAddress string
-			
-			if isSuperAdmin(userID) {
-				// Super admin: /setaddress 1 Toshkent, Amir Temur
-				parts := strings.SplitN(input, " ", 2)
-				if len(parts) < 2 {
-					bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, 
-						"❌ Noto'g'ri format\n\n"+
-						"To'g'ri format:\n"+
-						"<code>/setaddress 1 Toshkent, Amir Temur 123</code>\n"+
-						"<code>/setaddress 1 https://maps.app.goo.gl/xxx</code>"))
-					continue
-				}
-				
-				var err error
-				pharmacyID, err = strconv.Atoi(parts[0])
-				if err != nil || pharmacyID < 1 || pharmacyID > 3 {
-					bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "❌ Dorixona raqami 1, 2 yoki 3 bo'lishi kerak"))
-					continue
-				}
-				newAddress = strings.TrimSpace(parts[1])
-			} else {
-				// Oddiy admin: /setaddress Toshkent, Amir Temur
-				pharmacyID = getPharmacyID(userID)
-				newAddress = input
-				
-				if newAddress == "" {
-					bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, 
-						"❌ Noto'g'ri format\n\n"+
-						"To'g'ri format:\n"+
-						"<code>/setaddress Toshkent, Amir Temur 123</code>\n"+
-						"<code>/setaddress https://maps.app.goo.gl/xxx</code>"))
-					continue
-				}
+			importID, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(update.Message.Text, "/rollback ")))
+			if err != nil {
+				bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "❌ Noto'g'ri format\n\nTo'g'ri format: /rollback 15"))
+				continue
 			}
 
-			err := updateSetting(db, "address", newAddress, pharmacyID)
+			rec, err := excel.GetImport(db, importID)
 			if err != nil {
-				bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "❌ Xatolik: "+err.Error()))
+				bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "❌ "+err.Error()))
 				continue
 			}
 
-			// Barcha dorilarni yangilash
-			_, err = db.Exec("UPDATE medicines SET address = $1 WHERE pharmacy_id = $2", newAddress, pharmacyID)
+			// Oddiy admin faqat o'z dorixonasi importlarini bekor qiladi
+			if !isSuperAdmin(userID) && rec.PharmacyID != getPharmacyID(userID) {
+				bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "❌ Bu import sizning dorixonangizga tegishli emas"))
+				continue
+			}
+
+			reverted, err := excel.RollbackImport(db, importID)
 			if err != nil {
-				bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "⚠️ Settings yangilandi, lekin dorilar yangilanmadi"))
+				bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "❌ Rollback xato: "+err.Error()))
+				continue
 			}
 
-			msg := tgbotapi.NewMessage(update.Message.Chat.ID, 
-				fmt.Sprintf("✅ Dorixona %d manzili yangilandi!\n\n📍 Yangi: %s", pharmacyID, newAddress))
+			msg := tgbotapi.NewMessage(update.Message.Chat.ID, fmt.Sprintf(
+				"✅ <b>Dorixona %d import #%d oldidagi holatga qaytarildi</b>\n\n"+
+					"↩️ Bekor qilingan importlar: <b>%d</b> ta",
+				rec.PharmacyID, importID, reverted))
 			msg.ParseMode = "HTML"
 			bot.Send(msg)
 			continue
@@ -743,7 +2462,23 @@ func main() {
 					"To'g'ri format:\n"+
 					"<code>/upload 1</code> - Dorixona 1 uchun\n"+
 					"<code>/upload 2</code> - Dorixona 2 uchun\n"+
-					"<code>/upload 3</code> - Dorixona 3 uchun"))
+					"<code>/upload 3</code> - Dorixona 3 uchun\n"+
+					"<code>/upload all</code> - har bir sheet o'z dorixonasiga"))
+				continue
+			}
+
+			// Ko'p sheetli workbook - har bir sheet alohida dorixonaga
+			if parts[1] == "all" {
+				delete(uploadSession, userID)
+				workbookSession[userID] = true
+
+				msg := tgbotapi.NewMessage(update.Message.Chat.ID,
+					"✅ Tayyor!\n\n"+
+						"📤 Endi ko'p sheetli Excel faylni yuboring\n\n"+
+						"📄 Sheet nomi dorixona nomi yoki raqamiga mos kelsa avtomatik biriktiriladi, "+
+						"qolganlarini tugmalar orqali tanlaysiz")
+				msg.ParseMode = "HTML"
+				bot.Send(msg)
 				continue
 			}
 
@@ -755,6 +2490,7 @@ func main() {
 
 			// Session'da saqlab qo'yish
 			uploadSession[userID] = pharmacyID
+			delete(workbookSession, userID)
 
 			pharmacyName := getSetting(db, "name", pharmacyID)
 			if pharmacyName == "" {
@@ -770,13 +2506,67 @@ func main() {
 			continue
 		}
 
-		// Excel fayl yuklash
+		// Excel / CSV fayl yuklash
 		if update.Message.Document != nil {
 			if !isAdmin(userID) {
 				bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "❌ Siz admin emassiz"))
 				continue
 			}
 			
+			// Super admin - ko'p sheetli workbook (/upload all)
+			if isSuperAdmin(userID) && workbookSession[userID] {
+				delete(workbookSession, userID)
+
+				file, _ := bot.GetFile(tgbotapi.FileConfig{FileID: update.Message.Document.FileID})
+				resp, err := http.Get(file.Link(bot.Token))
+				if err != nil {
+					bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "❌ Fayl yuklanmadi"))
+					continue
+				}
+				data, err := io.ReadAll(resp.Body)
+				resp.Body.Close()
+				if err != nil {
+					bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "❌ Fayl yuklanmadi"))
+					continue
+				}
+
+				sheets, err := excel.WorkbookSheets(data)
+				if err != nil {
+					bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "❌ Faylni o'qishda xato: "+err.Error()))
+					continue
+				}
+
+				// Eskirgan workbooklarni tozalash
+				for id, wb := range pendingWorkbooks {
+					if time.Since(wb.created) > excel.StagedImportTTL {
+						delete(pendingWorkbooks, id)
+					}
+				}
+
+				pharmacies := getAllPharmacies(db)
+				wb := &pendingWorkbook{
+					fileName: update.Message.Document.FileName,
+					data:     data,
+					sheets:   sheets,
+					mapping:  make(map[string]int),
+					userID:   userID,
+					created:  time.Now(),
+				}
+				for _, sheet := range sheets {
+					wb.mapping[sheet] = matchSheetPharmacy(sheet, pharmacies)
+				}
+
+				wbID := strconv.FormatInt(time.Now().UnixNano(), 36)
+				pendingWorkbooks[wbID] = wb
+
+				text, keyboard := workbookMappingMessage(db, wbID, wb)
+				msg := tgbotapi.NewMessage(update.Message.Chat.ID, text)
+				msg.ParseMode = "HTML"
+				msg.ReplyMarkup = keyboard
+				bot.Send(msg)
+				continue
+			}
+
 			var pharmacyID int
 			
 			// Super admin uchun session'dan olish
@@ -826,24 +2616,121 @@ func main() {
 				continue
 			}
 		
+			// Holat xabari - har bir bosqichda tahrirlanadi, oxirida preview ga aylanadi
+			fileName := update.Message.Document.FileName
+			header := fmt.Sprintf("📁 <code>%s</code>\n🏪 Dorixona %d\n\n", html.EscapeString(fileName), pharmacyID)
+			status := newImportStatus(bot, update.Message.Chat.ID, header+"📥 Fayl yuklanmoqda...")
+
 			file, _ := bot.GetFile(tgbotapi.FileConfig{FileID: update.Message.Document.FileID})
 			url := file.Link(bot.Token)
 		
 			resp, err := http.Get(url)
 			if err != nil {
-				bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "❌ Fayl yuklanmadi"))
+				status.Finish(header+"❌ Fayl yuklanmadi", nil)
 				continue
 			}
-			defer resp.Body.Close()
+			// Fayl xotiraga alohida o'qilmaydi - parser to'g'ridan-to'g'ri javob oqimidan o'qiydi
+			size := int64(update.Message.Document.FileSize)
+			body := &progressReader{r: resp.Body, onRead: func(read int64) {
+				if size > 0 {
+					status.Update(fmt.Sprintf("%s📥 Fayl yuklanmoqda va o'qilmoqda: %d%%", header, read*100/size))
+				}
+			}}
 
-			chatID := strconv.FormatInt(update.Message.Chat.ID, 10)
-			err = excel.UploadExcel(db, update.Message.Document.FileName, resp.Body, botToken, chatID, phone, address, pharmacyID)
+			// Avval preview - bazaga faqat Confirm bosilganda yoziladi (API va papka bilan bir xil parse)
+			staged, err := stageFromReader(db, pharmacyID, fileName, body, "")
+			resp.Body.Close()
 			if err != nil {
 				fmt.Println("error:", err)
-				bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "❌ DB ga yozishda xato: "+err.Error()))
+				status.Finish(header+"❌ Faylni o'qishda xato: "+html.EscapeString(err.Error()), nil)
 				continue
 			}
-		
+
+			status.Update(fmt.Sprintf("%s🔎 %d ta dori bazadagi bilan solishtirilmoqda...", header, len(staged.Medicines)))
+			preview, err := staged.Preview(db)
+			if err != nil {
+				fmt.Println("error:", err)
+				status.Finish(header+"❌ DB error: "+html.EscapeString(err.Error()), nil)
+				continue
+			}
+
+			staged.UploadedBy = userID
+			importID := stageImport(staged, userID, update.Message.Chat.ID)
+
+			// Standart rejim tugmasi birinchi turadi
+			mergeBtn := tgbotapi.NewInlineKeyboardButtonData("✅ Qo'shish/yangilash", "import_merge:"+importID)
+			replaceBtn := tgbotapi.NewInlineKeyboardButtonData("🔁 To'liq sinxron", "import_replace:"+importID)
+			modeRow := tgbotapi.NewInlineKeyboardRow(mergeBtn, replaceBtn)
+			if staged.Mode == excel.ModeReplace {
+				modeRow = tgbotapi.NewInlineKeyboardRow(replaceBtn, mergeBtn)
+			}
+			keyboard := tgbotapi.NewInlineKeyboardMarkup(
+				modeRow,
+				tgbotapi.NewInlineKeyboardRow(
+					tgbotapi.NewInlineKeyboardButtonData("❌ Bekor qilish", "import_cancel:"+importID),
+				),
+			)
+			status.Finish(formatImportPreview(staged, preview), &keyboard)
+
+			// Muammoli qatorlar hisobotini fayl sifatida yuborish
+			sendIssueReport(bot, update.Message.Chat.ID, staged, strings.TrimSuffix(fileName, filepath.Ext(fileName)))
+			continue
+		}
+
+		// Rasm - qutidagi shtrix-kodni o'qib qidirish
+		if len(update.Message.Photo) > 0 {
+			// Eng katta o'lcham oxirida keladi
+			photo := update.Message.Photo[len(update.Message.Photo)-1]
+			file, _ := bot.GetFile(tgbotapi.FileConfig{FileID: photo.FileID})
+			resp, err := http.Get(file.Link(bot.Token))
+			if err != nil {
+				bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "❌ Rasm yuklanmadi"))
+				continue
+			}
+			codes, err := barcode.Decode(resp.Body)
+			resp.Body.Close()
+			if err != nil {
+				bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "❌ "+err.Error()))
+				continue
+			}
+
+			var found, invalid []string
+			response := ""
+			for _, raw := range codes {
+				code, ok := excel.NormalizeBarcode(raw)
+				if !ok {
+					invalid = append(invalid, raw)
+					continue
+				}
+				found = append(found, code)
+				if response, err = searchMedicines(db, "m.barcode = $1", code); err != nil || response != "" {
+					break
+				}
+			}
+
+			switch {
+			case err != nil:
+				bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "❌ DB error"))
+			case len(found) == 0 && len(invalid) > 0:
+				// Kod o'qildi, lekin EAN-8/EAN-13 emas (masalan oddiy QR matn)
+				msg := tgbotapi.NewMessage(update.Message.Chat.ID, fmt.Sprintf(
+					"⚠️ Kod o'qildi, lekin u EAN shtrix-kodi emas: <code>%s</code>\n\nQutidagi EAN-13 yoki EAN-8 shtrix-kodni suratga oling yoki raqamlarini yozib yuboring",
+					html.EscapeString(strings.Join(invalid, ", "))))
+				msg.ParseMode = "HTML"
+				bot.Send(msg)
+			case len(found) == 0:
+				bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID,
+					"🔍 Rasmda shtrix-kod topilmadi\n\nKodni yaqinroqdan, tekis va yorug' joyda suratga oling yoki raqamlarini yozib yuboring"))
+			case response == "":
+				msg := tgbotapi.NewMessage(update.Message.Chat.ID,
+					fmt.Sprintf("❌ Shtrix-kod <code>%s</code> bo'yicha dori topilmadi", strings.Join(found, ", ")))
+				msg.ParseMode = "HTML"
+				bot.Send(msg)
+			default:
+				msg := tgbotapi.NewMessage(update.Message.Chat.ID, response)
+				msg.ParseMode = "HTML"
+				bot.Send(msg)
+			}
 			continue
 		}
 
@@ -854,65 +2741,24 @@ func main() {
 				continue
 			}
 		
-			searchRussian := translitToRussian(search)
-			
-			rows, err := db.Query(`
-				SELECT m.name, m.price, m.count, m.manufacturer, m.phone, m.address, 
-				       m.description, m.category, m.pharmacy_id, s.value as pharmacy_name
-				FROM medicines m
-				LEFT JOIN settings s ON s.pharmacy_id = m.pharmacy_id AND s.key = 'name'
-				WHERE m.name ILIKE $1 OR m.name ILIKE $2
-				ORDER BY m.pharmacy_id, m.name
-				LIMIT 30
-			`, "%"+search+"%", "%"+searchRussian+"%")
+			// 8 yoki 13 raqam - avval shtrix-kod bo'yicha aniq qidirish
+			response := ""
+			var err error
+			if code, ok := excel.NormalizeBarcode(search); ok {
+				response, err = searchMedicines(db, "m.barcode = $1", code)
+			}
+			if err == nil && response == "" {
+				response, err = fuzzySearchMedicines(db, search)
+			}
 			if err != nil {
 				bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "❌ DB error"))
 				continue
 			}
 		
-			var response strings.Builder
-			found := false
-			for rows.Next() {
-				var name, manufacturer, phone, address string
-				var description, category, pharmacyName sql.NullString
-				var price, count, pharmacyID int
-				
-				rows.Scan(&name, &price, &count, &manufacturer, &phone, &address, 
-					&description, &category, &pharmacyID, &pharmacyName)
-		
-				// Dorixona nomini ko'rsatish
-				if pharmacyName.Valid && pharmacyName.String != "" {
-					response.WriteString(fmt.Sprintf("🏪 <b>%s</b>\n", pharmacyName.String))
-				} else {
-					response.WriteString(fmt.Sprintf("🏪 <b>Dorixona %d</b>\n", pharmacyID))
-				}
-				
-				response.WriteString(fmt.Sprintf(
-					"💊 <b>%s</b>\n"+
-					"🧮 Miqdor: <b>%d</b> dona\n"+
-					"💰 Narx: <b>%d</b> so'm\n"+
-					"📞 Telefon: <code>%s</code>\n"+
-					"📍 Manzil: %s\n",
-					name, count, price, phone, address,
-				))
-				
-				if category.Valid && category.String != "" {
-					response.WriteString(fmt.Sprintf("🏷 Kategoriya: %s\n", category.String))
-				}
-				
-				if description.Valid && description.String != "" {
-					response.WriteString(fmt.Sprintf("ℹ️ %s\n", description.String))
-				}
-				
-				response.WriteString("\n")
-				found = true
-			}
-			rows.Close()
-		
-			if !found {
+			if response == "" {
 				bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "❌ Topilmadi"))
 			} else {
-				msg := tgbotapi.NewMessage(update.Message.Chat.ID, response.String())
+				msg := tgbotapi.NewMessage(update.Message.Chat.ID, response)
 				msg.ParseMode = "HTML"
 				bot.Send(msg)
 			}
@@ -920,6 +2766,138 @@ func main() {
 	}
 }
 
+// searchSimilarityThreshold - pg_trgm word_similarity chegarasi: bitta harf xato yozilgan
+// 9-10 harfli nom taxminan 0.5-0.6 beradi (standart 0.6 - juda qattiq)
+const searchSimilarityThreshold = "0.45"
+
+// likeEscape - foydalanuvchi matnidagi % va _ ILIKE shabloni bo'lib qolmasin
+func likeEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// fuzzySearchMedicines - nom bo'yicha xatoga chidamli qidiruv (pg_trgm). Lotin matn kirillga ham
+// o'giriladi. Tartib: aniq mos, boshlanishi mos, barcha so'zlar bor (istalgan tartibda), o'xshash.
+func fuzzySearchMedicines(db *sql.DB, search string) (string, error) {
+	variants := []string{strings.ToLower(strings.Join(strings.Fields(search), " "))}
+	if ru := translitToRussian(variants[0]); ru != variants[0] {
+		variants = append(variants, ru)
+	}
+
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	var exact, prefix, words, similar, score []string
+	for _, v := range variants {
+		p := arg(v)
+		exact = append(exact, fmt.Sprintf("lower(m.name) = %s", p))
+		similar = append(similar, fmt.Sprintf("%s <%% m.name", p))
+		score = append(score, fmt.Sprintf("word_similarity(%s, m.name)", p))
+		prefix = append(prefix, fmt.Sprintf("m.name ILIKE %s", arg(likeEscape(v)+"%")))
+
+		// So'zlar tartibi muhim emas: "400 ibuprofen" = "Ibuprofen 400"
+		var all []string
+		for _, w := range strings.Fields(v) {
+			all = append(all, fmt.Sprintf("m.name ILIKE %s", arg("%"+likeEscape(w)+"%")))
+		}
+		words = append(words, "("+strings.Join(all, " AND ")+")")
+	}
+
+	where := strings.Join(words, " OR ") + " OR " + strings.Join(similar, " OR ")
+	order := fmt.Sprintf(`CASE
+			WHEN %s THEN 0
+			WHEN %s THEN 1
+			WHEN %s THEN 2
+			ELSE 3
+		END, GREATEST(%s) DESC, m.pharmacy_id, m.name`,
+		strings.Join(exact, " OR "), strings.Join(prefix, " OR "), strings.Join(words, " OR "), strings.Join(score, ", "))
+
+	// <% operatori chegarasi faqat shu transaction uchun o'rnatiladi (connection pool)
+	tx, err := db.Begin()
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec("SELECT set_config('pg_trgm.word_similarity_threshold', $1, true)", searchSimilarityThreshold); err != nil {
+		return "", err
+	}
+	return queryMedicineCards(tx, where, order, args...)
+}
+
+// searchMedicines - BARCHA dorixonalardan qidirish, natija kartochkalar matni (topilmasa bo'sh)
+func searchMedicines(db *sql.DB, where string, args ...interface{}) (string, error) {
+	return queryMedicineCards(db, where, "m.pharmacy_id, m.name", args...)
+}
+
+// queryMedicineCards - dorilar kartochkalari; db *sql.DB yoki *sql.Tx
+func queryMedicineCards(db interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}, where, order string, args ...interface{}) (string, error) {
+	rows, err := db.Query(`
+		SELECT m.name, m.price, `+excel.AvailableCountSQL+`, m.manufacturer, m.phone, m.address, 
+		       m.barcode, m.description, m.category, m.pharmacy_id, s.value as pharmacy_name,
+		       `+excel.NearestExpirySQL+`
+		FROM medicines m
+		LEFT JOIN settings s ON s.pharmacy_id = m.pharmacy_id AND s.key = 'name'
+		WHERE `+where+`
+		ORDER BY `+order+`
+		LIMIT 30
+	`, args...)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	var response strings.Builder
+	for rows.Next() {
+		var name, manufacturer, phone, address string
+		var barcode, description, category, pharmacyName sql.NullString
+		var expiry sql.NullTime
+		var count, pharmacyID int
+		var price money.Money
+		
+		rows.Scan(&name, &price, &count, &manufacturer, &phone, &address, 
+			&barcode, &description, &category, &pharmacyID, &pharmacyName, &expiry)
+
+		// Dorixona nomini ko'rsatish
+		if pharmacyName.Valid && pharmacyName.String != "" {
+			response.WriteString(fmt.Sprintf("🏪 <b>%s</b>\n", pharmacyName.String))
+		} else {
+			response.WriteString(fmt.Sprintf("🏪 <b>Dorixona %d</b>\n", pharmacyID))
+		}
+		
+		response.WriteString(fmt.Sprintf(
+			"💊 <b>%s</b>\n"+
+			"🧮 Miqdor: <b>%d</b> dona\n"+
+			"💰 Narx: <b>%s</b> so'm\n"+
+			"📞 Telefon: <code>%s</code>\n"+
+			"📍 Manzil: %s\n",
+			name, count, price.Format(), phone, address,
+		))
+		
+		if expiry.Valid {
+			response.WriteString(fmt.Sprintf("⏳ Yaroqlilik muddati: %s gacha\n", expiry.Time.Format("02.01.2006")))
+		}
+		
+		if barcode.Valid && barcode.String != "" {
+			response.WriteString(fmt.Sprintf("🔢 Shtrix-kod: <code>%s</code>\n", barcode.String))
+		}
+		
+		if category.Valid && category.String != "" {
+			response.WriteString(fmt.Sprintf("🏷 Kategoriya: %s\n", category.String))
+		}
+		
+		if description.Valid && description.String != "" {
+			response.WriteString(fmt.Sprintf("ℹ️ %s\n", description.String))
+		}
+		
+		response.WriteString("\n")
+	}
+	return response.String(), rows.Err()
+}
+
 func connectDB() *sql.DB {
 	// Birinchi DATABASE_URL ni tekshirish (Railway, Fly.io uchun)
 	databaseURL := os.Getenv("DATABASE_URL")
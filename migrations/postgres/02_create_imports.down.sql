@@ -0,0 +1,7 @@
+-- Migration Rollback: Import tarixi jadvallarini o'chirish
+
+DROP INDEX IF EXISTS idx_import_changes_import;
+DROP INDEX IF EXISTS idx_imports_pharmacy;
+
+DROP TABLE IF EXISTS import_changes CASCADE;
+DROP TABLE IF EXISTS imports CASCADE;
@@ -0,0 +1,44 @@
+-- Migration UP: Import tarixi va rollback uchun jadvallar
+-- Har bir yuklangan fayl va u o'zgartirgan dorilarning oldingi holati saqlanadi
+
+-- 1. Imports jadvali - kim, qachon, qaysi faylni yukladi
+CREATE TABLE IF NOT EXISTS imports (
+    id SERIAL PRIMARY KEY,
+    pharmacy_id INT NOT NULL,
+    uploaded_by BIGINT NOT NULL DEFAULT 0,
+    file_name VARCHAR(255) NOT NULL,
+    checksum VARCHAR(64),
+    mode VARCHAR(20) NOT NULL DEFAULT 'merge',
+    saved INT NOT NULL DEFAULT 0,
+    inserted INT NOT NULL DEFAULT 0,
+    removed INT NOT NULL DEFAULT 0,
+    skipped INT NOT NULL DEFAULT 0,
+    duplicates INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT NOW(),
+    rolled_back_at TIMESTAMP
+);
+
+-- 2. Import_changes jadvali - import oldidan dorining holati
+-- existed = false bo'lsa dori import bilan yangi qo'shilgan (rollback'da o'chiriladi)
+CREATE TABLE IF NOT EXISTS import_changes (
+    id SERIAL PRIMARY KEY,
+    import_id INT NOT NULL REFERENCES imports(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    existed BOOLEAN NOT NULL,
+    price INT,
+    count INT,
+    manufacturer VARCHAR(255),
+    phone VARCHAR(50),
+    address TEXT,
+    description TEXT,
+    category VARCHAR(100),
+    updated_at TIMESTAMP
+);
+
+-- 3. Index'lar
+CREATE INDEX IF NOT EXISTS idx_imports_pharmacy ON imports(pharmacy_id, id DESC);
+CREATE INDEX IF NOT EXISTS idx_import_changes_import ON import_changes(import_id);
+
+-- 4. Izohlar
+COMMENT ON TABLE imports IS 'Yuklangan fayllar tarixi';
+COMMENT ON TABLE import_changes IS 'Import oldidan dorilar holati (rollback uchun)';
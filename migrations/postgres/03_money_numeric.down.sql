@@ -0,0 +1,8 @@
+-- Migration Rollback: Narxlarni yana INT ga qaytarish (tiyinlar yaxlitlanadi)
+
+ALTER TABLE import_changes ALTER COLUMN price TYPE INT USING ROUND(price)::INT;
+
+ALTER TABLE medicines ALTER COLUMN price TYPE INT USING ROUND(price)::INT;
+ALTER TABLE medicines ALTER COLUMN price SET DEFAULT 0;
+
+COMMENT ON COLUMN medicines.price IS NULL;
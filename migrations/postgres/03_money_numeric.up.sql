@@ -0,0 +1,12 @@
+-- Migration UP: Narxlarni aniq saqlash
+-- INT o'rniga NUMERIC(14,2) - tiyinlar yo'qolmaydi ("12 450,50")
+
+-- 1. Medicines jadvali
+ALTER TABLE medicines ALTER COLUMN price TYPE NUMERIC(14,2) USING price::NUMERIC(14,2);
+ALTER TABLE medicines ALTER COLUMN price SET DEFAULT 0;
+
+-- 2. Import_changes jadvali (rollback uchun eski narxlar)
+ALTER TABLE import_changes ALTER COLUMN price TYPE NUMERIC(14,2) USING price::NUMERIC(14,2);
+
+-- 3. Izohlar
+COMMENT ON COLUMN medicines.price IS 'Narx, so''m (2 xona kasr - tiyin)';
@@ -0,0 +1,6 @@
+-- Migration Rollback: Shtrix-kod ustunini o'chirish
+
+DROP INDEX IF EXISTS idx_medicines_barcode;
+
+ALTER TABLE import_changes DROP COLUMN IF EXISTS barcode;
+ALTER TABLE medicines DROP COLUMN IF EXISTS barcode;
@@ -0,0 +1,13 @@
+-- Migration UP: Dorilarga shtrix-kod (EAN-13 / EAN-8) qo'shish
+
+-- 1. Medicines jadvali
+ALTER TABLE medicines ADD COLUMN IF NOT EXISTS barcode VARCHAR(14);
+
+-- 2. Import_changes jadvali (rollback uchun)
+ALTER TABLE import_changes ADD COLUMN IF NOT EXISTS barcode VARCHAR(14);
+
+-- 3. Index - shtrix-kod bo'yicha aniq qidirish
+CREATE INDEX IF NOT EXISTS idx_medicines_barcode ON medicines(barcode) WHERE barcode IS NOT NULL;
+
+-- 4. Izohlar
+COMMENT ON COLUMN medicines.barcode IS 'EAN-13 yoki EAN-8 shtrix-kod';
@@ -0,0 +1,8 @@
+-- Migration Rollback: Partiyalar jadvallarini o'chirish
+
+DROP INDEX IF EXISTS idx_import_batches_import;
+DROP INDEX IF EXISTS idx_batches_expiry;
+DROP INDEX IF EXISTS idx_batches_medicine;
+
+DROP TABLE IF EXISTS import_batches CASCADE;
+DROP TABLE IF EXISTS medicine_batches CASCADE;
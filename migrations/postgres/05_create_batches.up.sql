@@ -0,0 +1,32 @@
+-- Migration UP: Dori partiyalari (seriya va yaroqlilik muddati)
+-- Bir dorining bir nechta partiyasi bo'lishi mumkin; medicines.count - partiyalar yig'indisi
+
+-- 1. Medicine_batches jadvali
+CREATE TABLE IF NOT EXISTS medicine_batches (
+    id SERIAL PRIMARY KEY,
+    pharmacy_id INT NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    series VARCHAR(100) NOT NULL DEFAULT '',
+    expiry_date DATE,
+    count INT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+-- 2. Import_batches jadvali - import oldidan partiyalar holati (rollback uchun)
+CREATE TABLE IF NOT EXISTS import_batches (
+    id SERIAL PRIMARY KEY,
+    import_id INT NOT NULL REFERENCES imports(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    series VARCHAR(100) NOT NULL DEFAULT '',
+    expiry_date DATE,
+    count INT NOT NULL DEFAULT 0
+);
+
+-- 3. Index'lar
+CREATE INDEX IF NOT EXISTS idx_batches_medicine ON medicine_batches(pharmacy_id, name);
+CREATE INDEX IF NOT EXISTS idx_batches_expiry ON medicine_batches(expiry_date);
+CREATE INDEX IF NOT EXISTS idx_import_batches_import ON import_batches(import_id);
+
+-- 4. Izohlar
+COMMENT ON TABLE medicine_batches IS 'Dori partiyalari: seriya, yaroqlilik muddati va qoldiq';
+COMMENT ON TABLE import_batches IS 'Import oldidan partiyalar holati (rollback uchun)';
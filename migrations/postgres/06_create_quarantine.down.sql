@@ -0,0 +1,5 @@
+-- Migration Rollback: Karantin jadvalini o'chirish
+
+DROP INDEX IF EXISTS idx_quarantine_pharmacy;
+
+DROP TABLE IF EXISTS import_quarantine CASCADE;
@@ -0,0 +1,29 @@
+-- Migration UP: Shubhali qatorlar karantini
+-- Import paytida tekshiruvdan o'tmagan dorilar medicines ga yozilmaydi, admin tasdiqlashini kutadi
+
+-- 1. Import_quarantine jadvali
+CREATE TABLE IF NOT EXISTS import_quarantine (
+    id SERIAL PRIMARY KEY,
+    pharmacy_id INT NOT NULL,
+    import_id INT REFERENCES imports(id) ON DELETE SET NULL,
+    name VARCHAR(255) NOT NULL,
+    price NUMERIC(14,2) NOT NULL DEFAULT 0,
+    count INT NOT NULL DEFAULT 0,
+    manufacturer VARCHAR(255),
+    barcode VARCHAR(14),
+    phone VARCHAR(50),
+    address TEXT,
+    description TEXT,
+    category VARCHAR(100),
+    batches JSONB,
+    old_price NUMERIC(14,2),
+    old_count INT,
+    reason TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+-- 2. Index'lar
+CREATE INDEX IF NOT EXISTS idx_quarantine_pharmacy ON import_quarantine(pharmacy_id, id);
+
+-- 3. Izohlar
+COMMENT ON TABLE import_quarantine IS 'Tekshiruvdan o''tmagan, admin tasdiqlashini kutayotgan dorilar';
@@ -0,0 +1,5 @@
+-- Migration Rollback: Trigram qidiruv indexini o'chirish
+
+DROP INDEX IF EXISTS idx_medicines_name_trgm;
+
+DROP EXTENSION IF EXISTS pg_trgm;
@@ -0,0 +1,11 @@
+-- Migration UP: Xatoga chidamli qidiruv (pg_trgm)
+-- "parasetamol", "ibuprofen 400" yoki bitta harfi xato yozilgan nomlar ham topilsin
+
+-- 1. Trigram kengaytmasi
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- 2. GIN index - ILIKE '%...%' va o'xshashlik (<%) operatorlari uchun
+CREATE INDEX IF NOT EXISTS idx_medicines_name_trgm ON medicines USING GIN (name gin_trgm_ops);
+
+-- 3. Izohlar
+COMMENT ON INDEX idx_medicines_name_trgm IS 'Dori nomi bo''yicha trigram qidiruv';